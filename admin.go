@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/store"
+	"strconv"
+)
+
+// promptTemplateFromForm reads and validates the prompt template of the admin form.
+func promptTemplateFromForm(r *http.Request) (model.PromptTemplate, clients.PromptMode, error) {
+	t := model.PromptTemplate{
+		Name:     r.FormValue("name"),
+		Mode:     r.FormValue("mode"),
+		Template: r.FormValue(model.Template),
+	}
+	t.ID, _ = strconv.ParseInt(r.FormValue("id"), 10, 64)
+
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err == nil && count <= 0 {
+		err = fmt.Errorf("%d is not positive", count)
+	}
+	if err != nil {
+		return t, clients.PromptMode{}, fmt.Errorf("invalid number of items: %w", err)
+	}
+	t.Count = count
+
+	if t.Name == "" {
+		return t, clients.PromptMode{}, fmt.Errorf("the template must have a name")
+	}
+
+	mode, err := clients.WithTemplate(t)
+	return t, mode, err
+}
+
+// registerAdminHandlers registers the prompt templates pages, only the
+// administrator can use them.
+func registerAdminHandlers(auth *auth, st *store.Store, llm *clients.LLMClient, usage *usageTracker, keys *keyring) {
+	renderPrompts := func(w http.ResponseWriter, r *http.Request, editing *model.PromptTemplate, formErr string) {
+		templates, err := st.PromptTemplates()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if editing == nil {
+			mode := clients.PromptModes()[0]
+			editing = &model.PromptTemplate{Mode: mode.Key, Template: mode.Template, Count: clients.DefaultPromptCount}
+		}
+
+		component := components.AdminPrompts(templates, clients.PromptModes(), *editing, formErr, auth.csrfToken(adminName))
+		component.Render(r.Context(), w)
+	}
+
+	http.HandleFunc("GET /admin/prompts", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		renderPrompts(w, r, nil, "")
+	}))
+
+	http.HandleFunc("GET /admin/prompts/{id}", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		t, err := st.PromptTemplate(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if t == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		renderPrompts(w, r, t, "")
+	}))
+
+	http.HandleFunc("POST /admin/prompts", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		t, _, err := promptTemplateFromForm(r)
+		if err == nil {
+			err = st.SavePromptTemplate(&t)
+		}
+		if err != nil {
+			renderPrompts(w, r, &t, err.Error())
+			return
+		}
+
+		log.Printf("Saved prompt template %s (%d)", t.Name, t.ID)
+		http.Redirect(w, r, "/admin/prompts", http.StatusSeeOther)
+	}))
+
+	http.HandleFunc("POST /admin/prompts/{id}/delete", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err := st.DeletePromptTemplate(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/prompts", http.StatusSeeOther)
+	}))
+
+	// Renders the prompt that would be sent for the word of the form.
+	http.HandleFunc("POST /admin/prompts/preview", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		_, mode, err := promptTemplateFromForm(r)
+		if err != nil {
+			components.AdminError(err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		prompt, err := mode.Prompt(r.FormValue(model.Search), formLanguage(r))
+		if err != nil {
+			components.AdminError(err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		components.AdminPromptPreview(prompt).Render(r.Context(), w)
+	}))
+
+	// Runs the prompt against the LLM configured on the server.
+	http.HandleFunc("POST /admin/prompts/test", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		_, mode, err := promptTemplateFromForm(r)
+		if err != nil {
+			components.AdminError(err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		config := keys.sourceConfig(llm)
+		config.Usage, config.User = usage, adminName
+		client := config.LLMSource(model.SourceLLM)
+		if client == nil {
+			client = config.LLMSource(model.SourcePerplexity)
+		}
+		if client == nil {
+			components.AdminError("No LLM is configured to test the template").Render(r.Context(), w)
+			return
+		}
+		if err := usage.CheckBudget(adminName); err != nil {
+			components.AdminError(err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		res, err := client.QueryPrompt(mode, r.FormValue(model.Search), formLanguage(r))
+		if err != nil {
+			components.AdminError(err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		components.Result(client.Name+" · "+mode.Name, "", res.Elapsed, res.List, "").Render(r.Context(), w)
+	}))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+func TestPromptTemplateFromForm(t *testing.T) {
+	valid := url.Values{
+		"id":           {"4"},
+		"name":         {"Kids"},
+		"mode":         {clients.PromptExamples},
+		model.Template: {"{{.Count}} simple sentences with {{.Word}} in {{.Language}}\n{{.Format}}"},
+		"count":        {"3"},
+	}
+
+	tests := []struct {
+		name   string
+		change url.Values
+		// Substring of the expected error, empty if the form is valid
+		err string
+	}{
+		{name: "valid"},
+		{name: "no name", change: url.Values{"name": {""}}, err: "must have a name"},
+		{name: "no count", change: url.Values{"count": {""}}, err: "invalid number of items"},
+		{name: "negative count", change: url.Values{"count": {"-1"}}, err: "-1 is not positive"},
+		{name: "unknown mode", change: url.Values{"mode": {"poems"}}, err: "unknown prompt mode poems"},
+		{name: "invalid template", change: url.Values{model.Template: {"{{if .Word}}"}}, err: "invalid examples prompt template"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{}
+			for k, v := range valid {
+				form[k] = v
+			}
+			for k, v := range test.change {
+				form[k] = v
+			}
+			r := httptest.NewRequest("POST", "/admin/prompts", strings.NewReader(form.Encode()))
+			r.Header.Set(clients.ContentType, "application/x-www-form-urlencoded")
+
+			tmpl, mode, err := promptTemplateFromForm(r)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatal(err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("promptTemplateFromForm() = %v, want %q", err, test.err)
+			case test.err != "":
+				return
+			}
+
+			want := model.PromptTemplate{ID: 4, Name: "Kids", Mode: clients.PromptExamples, Template: valid.Get(model.Template), Count: 3}
+			if tmpl != want {
+				t.Errorf("template = %+v, want %+v", tmpl, want)
+			}
+			if mode.Name != "Kids" || mode.Count != 3 || mode.ListKey != "examples" {
+				t.Errorf("mode = %+v", mode)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sahib/components"
+	"sahib/model"
+	"strings"
+)
+
+// Name of the identity of the administrator.
+const adminName = "admin"
+
+const sessionCookie = "sahib_session"
+
+// How long the sessions last, in seconds.
+const sessionMaxAge = 30 * 24 * 3600
+
+// auth identifies the visitors with the tokens given to them: the token is
+// entered once on the login page, which sets a signed session cookie, or
+// sent by scripts as a bearer token. The administrator's token is read from
+// SAHIB_ADMIN_TOKEN, the admin pages are disabled without it. The users and
+// their tokens are read from the JSON file of SAHIB_USERS, their LLM usage is
+// tracked under their name.
+type auth struct {
+	adminToken string
+	// Token of each user
+	users map[string]string
+	// Signs the session cookies and the CSRF tokens
+	key []byte
+}
+
+// newAuth derives the signing key from SAHIB_SECRET, without it a random key
+// is used and the sessions end with the server.
+func newAuth() (*auth, error) {
+	a := &auth{adminToken: os.Getenv("SAHIB_ADMIN_TOKEN"), users: map[string]string{}}
+
+	if path := os.Getenv("SAHIB_USERS"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SAHIB_USERS: %w", err)
+		}
+		if err := json.Unmarshal(data, &a.users); err != nil {
+			return nil, fmt.Errorf("failed to parse SAHIB_USERS: %w", err)
+		}
+		for name, token := range a.users {
+			if !userName.MatchString(name) || name == adminName {
+				return nil, fmt.Errorf("invalid user name in SAHIB_USERS: %q", name)
+			}
+			if token == "" || token == a.adminToken {
+				return nil, fmt.Errorf("the token of %s in SAHIB_USERS is empty or the admin token", name)
+			}
+		}
+	}
+
+	if secret := os.Getenv("SAHIB_SECRET"); secret != "" {
+		sum := sha256.Sum256([]byte("session\x00" + secret))
+		a.key = sum[:]
+	} else {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.adminToken == "" {
+		log.Print("SAHIB_ADMIN_TOKEN isn't set, the admin pages are disabled")
+	}
+
+	return a, nil
+}
+
+// The names are part of the session cookies and of the usage page.
+var userName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// hasUsers returns whether users are configured, the anonymous visitors then
+// can't use the LLM sources.
+func (a *auth) hasUsers() bool {
+	return len(a.users) > 0
+}
+
+func (a *auth) sign(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tokenOf returns the token of an identity, empty if it doesn't exist.
+func (a *auth) tokenOf(name string) string {
+	if name == adminName {
+		return a.adminToken
+	}
+	return a.users[name]
+}
+
+// session returns the value of the session cookie of an identity, its token
+// is part of the signature so that changing it ends the sessions.
+func (a *auth) session(name string) string {
+	return name + "." + a.sign("session\x00"+name+"\x00"+a.tokenOf(name))
+}
+
+// login returns the identity owning the token.
+func (a *auth) login(token string) (string, bool) {
+	if a.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1 {
+		return adminName, true
+	}
+	for name, userToken := range a.users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(userToken)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// identify returns the identity of the request, empty for anonymous visitors.
+func (a *auth) identify(r *http.Request) string {
+	if token, ok := bearerToken(r); ok {
+		name, _ := a.login(token)
+		return name
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	name, _, _ := strings.Cut(cookie.Value, ".")
+	if a.tokenOf(name) == "" || !hmac.Equal([]byte(cookie.Value), []byte(a.session(name))) {
+		return ""
+	}
+	return name
+}
+
+// csrfToken is the token the forms of a session must send back with their
+// POST requests.
+func (a *auth) csrfToken(name string) string {
+	return a.sign("csrf\x00" + name)
+}
+
+// requireAdmin only lets the administrator through, the POST requests of a
+// session must also carry its CSRF token.
+func (a *auth) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	require := a.require(func(name string) bool { return name == adminName }, "Only the administrator can do this", next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.adminToken == "" {
+			http.Error(w, "The admin pages are disabled, set SAHIB_ADMIN_TOKEN to enable them", http.StatusForbidden)
+			return
+		}
+		require(w, r)
+	}
+}
+
+// requireLogin lets the administrator and the users through, with the same
+// checks as requireAdmin.
+func (a *auth) requireLogin(next http.HandlerFunc) http.HandlerFunc {
+	require := a.require(func(name string) bool { return name != "" }, "Log in to do this", next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.adminToken == "" && !a.hasUsers() {
+			http.Error(w, "Nobody can log in, set SAHIB_ADMIN_TOKEN or SAHIB_USERS", http.StatusForbidden)
+			return
+		}
+		require(w, r)
+	}
+}
+
+// requireToken only lets through the scripts sending the token of the
+// administrator or of a user, the session cookies aren't accepted so that the
+// JSON API isn't exposed to CSRF.
+func (a *auth) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := bearerToken(r)
+		if _, ok := a.login(token); !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, fmt.Errorf("send the token of a user in an Authorization: Bearer header"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// require redirects the visitors who aren't allowed to the login page, or
+// rejects their POST requests.
+func (a *auth) require(allowed func(name string) bool, denied string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := a.identify(r)
+		if !allowed(name) {
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, "/login?"+url.Values{model.Next: {r.URL.Path}}.Encode(), http.StatusSeeOther)
+				return
+			}
+			http.Error(w, denied, http.StatusUnauthorized)
+			return
+		}
+
+		// Scripts using the bearer token aren't exposed to CSRF
+		_, bearer := bearerToken(r)
+		if r.Method != http.MethodGet && !bearer && !hmac.Equal([]byte(r.FormValue(model.CSRF)), []byte(a.csrfToken(name))) {
+			http.Error(w, "Invalid or missing CSRF token, reload the page", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// safeNext returns the local path to go back to after logging in.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func (a *auth) registerHandlers() {
+	http.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		components.Login(safeNext(r.FormValue(model.Next)), "").Render(r.Context(), w)
+	})
+
+	http.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		next := safeNext(r.FormValue(model.Next))
+		name, ok := a.login(r.FormValue(model.Token))
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			components.Login(next, "Unknown token").Render(r.Context(), w)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    a.session(name),
+			Path:     "/",
+			MaxAge:   sessionMaxAge,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		log.Printf("%s logged in", name)
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	})
+
+	http.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+}
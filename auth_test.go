@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+// newTestAuth configures the administrator and a user named amina.
+func newTestAuth(t *testing.T, secret string) *auth {
+	t.Helper()
+
+	users := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(users, []byte(`{"amina": "amina-token"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SAHIB_ADMIN_TOKEN", "admin-token")
+	t.Setenv("SAHIB_USERS", users)
+	t.Setenv("SAHIB_SECRET", secret)
+
+	a, err := newAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func withSession(r *http.Request, value string) *http.Request {
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: value})
+	return r
+}
+
+func TestLogin(t *testing.T) {
+	a := newTestAuth(t, "secret")
+
+	for token, want := range map[string]string{"admin-token": adminName, "amina-token": "amina", "amina": "", "": ""} {
+		name, ok := a.login(token)
+		if name != want || ok != (want != "") {
+			t.Errorf("login(%q) = %q, %v, want %q", token, name, ok, want)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	a := newTestAuth(t, "secret")
+	amina := a.session("amina")
+	_, signature, _ := strings.Cut(amina, ".")
+	bearer := httptest.NewRequest("GET", "/", nil)
+	bearer.Header.Set("Authorization", "Bearer amina-token")
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    string
+	}{
+		{"anonymous", httptest.NewRequest("GET", "/", nil), ""},
+		{"session", withSession(httptest.NewRequest("GET", "/", nil), amina), "amina"},
+		{"admin session", withSession(httptest.NewRequest("GET", "/", nil), a.session(adminName)), adminName},
+		{"other name", withSession(httptest.NewRequest("GET", "/", nil), "admin."+signature), ""},
+		{"unknown user", withSession(httptest.NewRequest("GET", "/", nil), "omar."+signature), ""},
+		{"not signed", withSession(httptest.NewRequest("GET", "/", nil), "amina"), ""},
+		{"bearer token", bearer, "amina"},
+	}
+
+	for _, test := range tests {
+		if got := a.identify(test.request); got != test.want {
+			t.Errorf("%s: identify() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSessionSecret(t *testing.T) {
+	a := newTestAuth(t, "secret")
+	session := a.session("amina")
+
+	// The sessions survive a restart with the same secret
+	restarted := newTestAuth(t, "secret")
+	if got := restarted.identify(withSession(httptest.NewRequest("GET", "/", nil), session)); got != "amina" {
+		t.Errorf("identify() = %q after a restart", got)
+	}
+
+	other := newTestAuth(t, "other secret")
+	if got := other.identify(withSession(httptest.NewRequest("GET", "/", nil), session)); got != "" {
+		t.Errorf("identify() = %q with another secret", got)
+	}
+
+	// Changing the token of the user ends their sessions
+	restarted.users["amina"] = "new-token"
+	if got := restarted.identify(withSession(httptest.NewRequest("GET", "/", nil), session)); got != "" {
+		t.Errorf("identify() = %q after changing the token", got)
+	}
+}
+
+func TestNewAuthInvalidUsers(t *testing.T) {
+	for name, users := range map[string]string{
+		"admin name":   `{"admin": "token"}`,
+		"invalid name": `{"amina lee": "token"}`,
+		"empty token":  `{"amina": ""}`,
+		"admin token":  `{"amina": "admin-token"}`,
+		"not json":     `amina=token`,
+	} {
+		path := filepath.Join(t.TempDir(), "users.json")
+		os.WriteFile(path, []byte(users), 0o600)
+		t.Setenv("SAHIB_ADMIN_TOKEN", "admin-token")
+		t.Setenv("SAHIB_USERS", path)
+
+		if _, err := newAuth(); err == nil {
+			t.Errorf("%s: expected an error for %s", name, users)
+		}
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	a := newTestAuth(t, "secret")
+	handler := a.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	post := func(session string, csrf string) *http.Request {
+		form := url.Values{model.CSRF: {csrf}}
+		r := httptest.NewRequest("POST", "/admin/prompts", strings.NewReader(form.Encode()))
+		r.Header.Set(clients.ContentType, "application/x-www-form-urlencoded")
+		if session != "" {
+			withSession(r, session)
+		}
+		return r
+	}
+	bearer := post("", "")
+	bearer.Header.Set("Authorization", "Bearer admin-token")
+
+	admin := a.session(adminName)
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    int
+	}{
+		{"anonymous page", httptest.NewRequest("GET", "/admin/prompts", nil), http.StatusSeeOther},
+		{"anonymous form", post("", a.csrfToken(adminName)), http.StatusUnauthorized},
+		{"user form", post(a.session("amina"), a.csrfToken("amina")), http.StatusUnauthorized},
+		{"admin page", withSession(httptest.NewRequest("GET", "/admin/prompts", nil), admin), http.StatusOK},
+		{"admin form", post(admin, a.csrfToken(adminName)), http.StatusOK},
+		{"missing CSRF token", post(admin, ""), http.StatusForbidden},
+		{"CSRF token of another session", post(admin, a.csrfToken("amina")), http.StatusForbidden},
+		{"bearer token", bearer, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, test.request)
+		if rec.Code != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, rec.Code, test.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/admin/prompts", nil))
+	if got := rec.Header().Get("Location"); got != "/login?next=%2Fadmin%2Fprompts" {
+		t.Errorf("redirected to %q", got)
+	}
+
+	// Without admin token nobody gets in
+	a.adminToken = ""
+	rec = httptest.NewRecorder()
+	handler(rec, post(admin, a.csrfToken(adminName)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("disabled admin pages: got %d", rec.Code)
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	for next, want := range map[string]string{
+		"/admin/keys":          "/admin/keys",
+		"":                     "/",
+		"https://evil.com":     "/",
+		"//evil.com/path":      "/",
+		"/\\evil.com":          "/",
+		"admin/keys":           "/",
+		"/usage?month=2026-10": "/usage?month=2026-10",
+	} {
+		if got := safeNext(next); got != want {
+			t.Errorf("safeNext(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package clients
+
+import (
+	"context"
+	"log"
+	"sahib/model"
+	"strings"
+	"sync"
+)
+
+// Number of elixir requests run at the same time when annotating sentences.
+const annotateConcurrency = 4
+
+// Maximum number of words kept in elixirCache, the oldest ones are dropped
+// first.
+const elixirCacheSize = 10000
+
+// wordCache keeps the annotations of the words already resolved, the same
+// words come back in most sentences.
+type wordCache struct {
+	mu   sync.Mutex
+	size int
+	m    map[string]model.AnnotatedWord
+	// Words in insertion order, to drop the oldest ones
+	order []string
+}
+
+func newWordCache(size int) *wordCache {
+	return &wordCache{size: size, m: map[string]model.AnnotatedWord{}}
+}
+
+func (c *wordCache) get(word string) (model.AnnotatedWord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	annotation, ok := c.m[word]
+	return annotation, ok
+}
+
+func (c *wordCache) add(word string, annotation model.AnnotatedWord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.m[word]; !ok {
+		c.order = append(c.order, word)
+	}
+	c.m[word] = annotation
+
+	for len(c.order) > c.size {
+		delete(c.m, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+var elixirCache = newWordCache(elixirCacheSize)
+
+// lexemeAnnotation returns the annotation of a lexeme for one of its forms,
+// the features of the inflected form being more telling than the ones of
+// the lemma.
+func lexemeAnnotation(text string, m *model.Morphology, form *model.InflectedForm) model.AnnotatedWord {
+	annotation := model.AnnotatedWord{Text: text, Word: true, Lemma: m.Lemma, Gloss: m.Gloss, Tag: m.Features.String()}
+	if form != nil {
+		annotation.Tag = form.Features.String()
+	}
+	return annotation
+}
+
+// annotateTokens maps the lexemes elixir found in a sentence back to its
+// words: a word gets the first lexeme having a form with the same letters,
+// else one of its forms the word contains (elixir splits the clitics), the
+// longest one. The words without a matching lexeme are left without
+// annotation.
+func annotateTokens(tokens []Token, lexemes []model.Translation) []model.AnnotatedWord {
+	words := make([]model.AnnotatedWord, len(tokens))
+	for i, tok := range tokens {
+		words[i] = model.AnnotatedWord{Text: tok.Text, Word: tok.Word}
+		if !tok.Word {
+			continue
+		}
+
+		want := skeleton(tok.Text)
+		var best *model.Morphology
+		var bestForm *model.InflectedForm
+		bestLen, exact := 0, false
+		for _, lexeme := range lexemes {
+			m := lexeme.Morphology
+			if m == nil {
+				continue
+			}
+			for j := range m.Forms {
+				form := skeleton(m.Forms[j].Arabic)
+				if form == "" {
+					continue
+				}
+				if form == want {
+					best, bestForm, exact = m, &m.Forms[j], true
+					break
+				}
+				if strings.Contains(want, form) && len([]rune(form)) > bestLen {
+					best, bestForm, bestLen = m, &m.Forms[j], len([]rune(form))
+				}
+			}
+			if exact {
+				break
+			}
+		}
+
+		if best != nil {
+			words[i] = lexemeAnnotation(tok.Text, best, bestForm)
+		}
+	}
+	return words
+}
+
+// annotate resolves the sentence with elixir, at once, unless all its words
+// are cached.
+func (c *wordCache) annotate(ctx context.Context, sentence string, resolve func(context.Context, string) ([]model.Translation, error)) ([]model.AnnotatedWord, error) {
+	tokens := Tokenize(sentence)
+	words := make([]model.AnnotatedWord, len(tokens))
+	cached := true
+	for i, tok := range tokens {
+		words[i] = model.AnnotatedWord{Text: tok.Text, Word: tok.Word}
+		if !tok.Word {
+			continue
+		}
+		if annotation, ok := c.get(tok.Text); ok {
+			words[i] = annotation
+		} else {
+			cached = false
+		}
+	}
+	if cached {
+		return words, nil
+	}
+
+	lexemes, err := resolve(ctx, sentence)
+	if err != nil {
+		return words, err
+	}
+
+	for i, annotation := range annotateTokens(tokens, lexemes) {
+		if !annotation.Word {
+			continue
+		}
+		// The word can be repeated in the sentence or cached by another row in
+		// the meantime, elixir doesn't always resolve it in every sentence
+		cached, ok := c.get(annotation.Text)
+		if ok && annotation.Lemma == "" {
+			annotation = cached
+		}
+		words[i] = annotation
+		if !ok {
+			c.add(annotation.Text, annotation)
+		}
+	}
+
+	return words, nil
+}
+
+// Annotate splits the arabic column of the rows in words and adds the lemma,
+// tag and gloss elixir finds for each of them, one request per row. The
+// words elixir couldn't resolve are left without annotation.
+func Annotate(ctx context.Context, rows []model.Translation) []model.Translation {
+	out := make([]model.Translation, len(rows))
+	copy(out, rows)
+
+	sem := make(chan struct{}, annotateConcurrency)
+	var wg sync.WaitGroup
+	for i := range out {
+		wg.Add(1)
+		go func(row *model.Translation) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			words, err := elixirCache.annotate(ctx, row.Arabic, resolveElixir)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to annotate %s: %s", row.Arabic, err)
+			}
+			row.Words = words
+		}(&out[i])
+	}
+	wg.Wait()
+
+	return out
+}
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"context"
+	"reflect"
+	"sahib/model"
+	"testing"
+)
+
+func TestAnnotateTokens(t *testing.T) {
+	verb := &model.Morphology{
+		Lemma: "كَتَب",
+		Gloss: "write",
+		Forms: []model.InflectedForm{
+			{Arabic: "كَتَبَ", Features: model.TagFeatures{POS: "verb", Aspect: "perfective"}},
+		},
+	}
+	noun := &model.Morphology{
+		Lemma:    "وَلَد",
+		Gloss:    "boy",
+		Features: model.TagFeatures{POS: "noun"},
+		Forms: []model.InflectedForm{
+			{Arabic: "وَلَد", Features: model.TagFeatures{POS: "noun", Case: "nominative"}},
+		},
+	}
+	lexemes := []model.Translation{{Arabic: verb.Lemma, Morphology: verb}, {Arabic: noun.Lemma, Morphology: noun}, {Arabic: "no morphology"}}
+
+	tests := []struct {
+		name     string
+		sentence string
+		want     []model.AnnotatedWord
+	}{
+		{
+			name:     "same letters",
+			sentence: "كتب",
+			want:     []model.AnnotatedWord{{Text: "كتب", Word: true, Lemma: "كَتَب", Gloss: "write", Tag: "verb, perfective"}},
+		},
+		{
+			name:     "clitic",
+			sentence: "والولد",
+			want:     []model.AnnotatedWord{{Text: "والولد", Word: true, Lemma: "وَلَد", Gloss: "boy", Tag: "noun, nominative"}},
+		},
+		{
+			name:     "sentence",
+			sentence: "كتب الولد؟",
+			want: []model.AnnotatedWord{
+				{Text: "كتب", Word: true, Lemma: "كَتَب", Gloss: "write", Tag: "verb, perfective"},
+				{Text: " "},
+				{Text: "الولد", Word: true, Lemma: "وَلَد", Gloss: "boy", Tag: "noun, nominative"},
+				{Text: "؟"},
+			},
+		},
+		{
+			name:     "unresolved",
+			sentence: "ذهب",
+			want:     []model.AnnotatedWord{{Text: "ذهب", Word: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := annotateTokens(Tokenize(test.sentence), lexemes)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("annotateTokens(%q) = %+v, want %+v", test.sentence, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWordCache(t *testing.T) {
+	cache := newWordCache(2)
+	cache.add("a", model.AnnotatedWord{Text: "a"})
+	cache.add("b", model.AnnotatedWord{Text: "b"})
+	cache.add("a", model.AnnotatedWord{Text: "a", Lemma: "updated"})
+	cache.add("c", model.AnnotatedWord{Text: "c"})
+
+	if _, ok := cache.get("a"); ok {
+		t.Errorf("the oldest word should be dropped")
+	}
+	for _, word := range []string{"b", "c"} {
+		if _, ok := cache.get(word); !ok {
+			t.Errorf("%s should be cached", word)
+		}
+	}
+	if len(cache.m) != 2 || len(cache.order) != 2 {
+		t.Errorf("cache has %d words and %d in order, want 2", len(cache.m), len(cache.order))
+	}
+}
+
+func TestWordCacheAnnotate(t *testing.T) {
+	boy := &model.Morphology{
+		Lemma: "وَلَد",
+		Gloss: "boy",
+		Forms: []model.InflectedForm{{Arabic: "وَلَد", Features: model.TagFeatures{POS: "noun"}}},
+	}
+	calls := 0
+	resolve := func(ctx context.Context, text string) ([]model.Translation, error) {
+		calls++
+		return []model.Translation{{Arabic: boy.Lemma, Morphology: boy}}, nil
+	}
+
+	cache := newWordCache(10)
+	// Cached by another row
+	went := model.AnnotatedWord{Text: "ذهب", Word: true, Lemma: "ذَهَب", Gloss: "go"}
+	cache.add("ذهب", went)
+
+	annotated := model.AnnotatedWord{Text: "ولد", Word: true, Lemma: "وَلَد", Gloss: "boy", Tag: "noun"}
+	want := []model.AnnotatedWord{annotated, {Text: " "}, went, {Text: " "}, annotated}
+	for i := 0; i < 2; i++ {
+		got, err := cache.annotate(context.Background(), "ولد ذهب ولد", resolve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("annotate() = %+v, want %+v", got, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("the sentence was resolved %d times, want 1", calls)
+	}
+}
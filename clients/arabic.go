@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type Token struct {
+	Text string
+	// Whether the token is an arabic word (as opposed to spaces, punctuation...)
+	Word bool
+}
+
+// isArabic tells whether r belongs to an arabic word, the harakat being part
+// of the words even though unicode doesn't count them in the arabic script.
+func isArabic(r rune) bool {
+	if unicode.Is(unicode.Mn, r) {
+		return r >= 0x0600 && r <= 0x06FF
+	}
+	return unicode.Is(unicode.Arabic, r) && !unicode.IsPunct(r) && !unicode.IsDigit(r)
+}
+
+// Tokenize splits a text in arabic words and the separators between them,
+// concatenating all the tokens gives back the original text.
+func Tokenize(text string) []Token {
+	tokens := []Token{}
+	var current strings.Builder
+	currentWord := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, Token{Text: current.String(), Word: currentWord})
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		word := isArabic(r)
+		if word != currentWord {
+			flush()
+			currentWord = word
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return tokens
+}
+
+// Normalize removes the diacritics and the tatweel from an arabic word.
+func Normalize(word string) string {
+	return strings.ReplaceAll(removeDiacritics(word), "ـ", "")
+}
+
+var alefs = strings.NewReplacer("أ", "ا", "إ", "ا", "آ", "ا", "ٱ", "ا")
+
+// NormalizeAlef replaces all the hamza/madda variants of alef by a bare alef
+// since they are often omitted in written texts.
+func NormalizeAlef(word string) string {
+	return alefs.Replace(word)
+}
+
+var (
+	// Ordered from the longest to the shortest so that we try to strip as
+	// much as possible first.
+	prefixes = []string{"وبال", "وكال", "فبال", "وال", "فال", "بال", "كال", "ولل", "لل", "ال", "وس", "و", "ف", "ب", "ك", "ل", "س"}
+	suffixes = []string{"كما", "هما", "تها", "ها", "هم", "هن", "كم", "كن", "نا", "ني", "ون", "ين", "ان", "ات", "ه", "ك", "ي", "ة"}
+)
+
+// minStem is the minimum number of letters of a word after stripping its clitics.
+const minStem = 2
+
+// Candidates returns the normalized word followed by the forms obtained by
+// stripping its most common clitics (conjunctions, prepositions, article and
+// attached pronouns), most likely first.
+func Candidates(word string) []string {
+	word = Normalize(word)
+	seen := map[string]bool{}
+	out := []string{}
+	add := func(w string) {
+		if len([]rune(w)) >= minStem && !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+
+	add(word)
+
+	stems := []string{word}
+	for _, p := range prefixes {
+		if stem, ok := strings.CutPrefix(word, p); ok && len([]rune(stem)) >= minStem {
+			stems = append(stems, stem)
+		}
+	}
+
+	for _, stem := range stems {
+		add(stem)
+		for _, s := range suffixes {
+			base, ok := strings.CutSuffix(stem, s)
+			if !ok || len([]rune(base)) < minStem {
+				continue
+			}
+			// The ta marbuta becomes a ta when a pronoun is attached (مدرستها).
+			if s == "تها" {
+				add(base + "ة")
+			}
+			if strings.HasSuffix(base, "ت") && s != "ات" {
+				add(strings.TrimSuffix(base, "ت") + "ة")
+			}
+			add(base)
+		}
+	}
+
+	return out
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// Gloss returns a short plain text version of an html definition.
+func Gloss(definition string, max int) string {
+	text := strings.Join(strings.Fields(htmlTags.ReplaceAllString(definition, " ")), " ")
+	runes := []rune(text)
+	if len(runes) > max {
+		return string(runes[:max]) + "…"
+	}
+
+	return text
+}
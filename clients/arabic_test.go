@@ -0,0 +1,70 @@
+package clients
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Token
+	}{
+		{"", []Token{}},
+		{"كتاب", []Token{{"كتاب", true}}},
+		{
+			"قرأتُ الكتابَ، ثم نمت.",
+			[]Token{{"قرأتُ", true}, {" ", false}, {"الكتابَ", true}, {"، ", false}, {"ثم", true}, {" ", false}, {"نمت", true}, {".", false}},
+		},
+		{"page 12 صفحة", []Token{{"page 12 ", false}, {"صفحة", true}}},
+	}
+
+	for _, test := range tests {
+		got := Tokenize(test.text)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %+v, want %+v", test.text, got, test.want)
+		}
+
+		text := strings.Builder{}
+		for _, tok := range got {
+			text.WriteString(tok.Text)
+		}
+		if text.String() != test.text {
+			t.Errorf("the tokens of %q give back %q", test.text, text.String())
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"مُعَلِّمَةٌ", []string{"معلمة", "معلم"}},
+		{"والكتاب", []string{"والكتاب", "كتاب", "الكتاب"}},
+		{"مدرسته", []string{"مدرسته", "مدرسة", "مدرست"}},
+		{"بمدرستها", []string{"بمدرستها", "بمدرسة", "بمدرس", "بمدرست", "مدرستها", "مدرسة", "مدرس", "مدرست"}},
+		// Too short to strip anything
+		{"من", []string{"من"}},
+	}
+
+	for _, test := range tests {
+		if got := Candidates(test.word); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Candidates(%q) = %q, want %q", test.word, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeAlef(t *testing.T) {
+	for input, want := range map[string]string{
+		"أكل":   "اكل",
+		"إسلام": "اسلام",
+		"آمن":   "امن",
+		"ٱلله":  "الله",
+	} {
+		if got := NormalizeAlef(input); got != want {
+			t.Errorf("NormalizeAlef(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
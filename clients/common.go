@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,7 +12,8 @@ import (
 
 const ContentType = "Content-Type"
 
-func elapsed(start time.Time) string {
+// Elapsed returns the time since start, rounded for the result cards.
+func Elapsed(start time.Time) string {
 	return time.Now().Sub(start).Truncate(10 * time.Millisecond).String()
 }
 
@@ -21,7 +23,22 @@ func queryURL(
 	body io.Reader,
 	headers map[string]string,
 	withHTTP2 bool) (*http.Response, error) {
-	req, err := http.NewRequest(typ, url, body)
+	return queryURLContext(context.Background(), typ, url, body, headers, withHTTP2)
+}
+
+// queryURLContext is like queryURL but the request is aborted when the context is done.
+func queryURLContext(
+	ctx context.Context,
+	typ string,
+	url string,
+	body io.Reader,
+	headers map[string]string,
+	withHTTP2 bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, typ, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:131.0) Gecko/20100101 Firefox/131.0")
 	req.Header.Set("Accept", "*/*")
 
@@ -29,10 +46,6 @@ func queryURL(
 		req.Header.Set(k, v)
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to build request: %w", err)
-	}
-
 	client := &http.Client{}
 	if withHTTP2 {
 		// http1 doesn't work with the maany website
@@ -0,0 +1,185 @@
+package clients
+
+import (
+	"fmt"
+	"sahib/model"
+	"strings"
+	"sync"
+)
+
+// Diacritizer adds the diacritics (tashkil) to arabic sentences.
+type Diacritizer interface {
+	// Diacritize returns the sentences with their diacritics in the same order,
+	// sentences that couldn't be vocalized are returned as is.
+	Diacritize(sentences []string, lang model.Language) ([]string, error)
+}
+
+// Maximum number of sentences kept by CachedDiacritizer, the oldest ones are
+// dropped first.
+const diacritizerCacheSize = 10000
+
+// CachedDiacritizer keeps the sentences vocalized by the underlying
+// diacritizer since they are usually slow.
+type CachedDiacritizer struct {
+	diacritizer Diacritizer
+
+	mu    sync.RWMutex
+	size  int
+	cache map[string]string
+	// Keys in insertion order, to drop the oldest ones
+	order []string
+}
+
+func NewCachedDiacritizer(d Diacritizer) *CachedDiacritizer {
+	return &CachedDiacritizer{diacritizer: d, size: diacritizerCacheSize, cache: map[string]string{}}
+}
+
+func cacheKey(sentence string, lang model.Language) string {
+	return lang.Code + "\x00" + sentence
+}
+
+// Cached returns the vocalized sentences if they were all already vocalized
+// by a previous call to Diacritize.
+func (c *CachedDiacritizer) Cached(sentences []string, lang model.Language) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]string, len(sentences))
+	for i, sentence := range sentences {
+		vocalized, ok := c.cache[cacheKey(sentence, lang)]
+		if !ok {
+			return sentences, false
+		}
+		out[i] = vocalized
+	}
+
+	return out, true
+}
+
+func (c *CachedDiacritizer) Diacritize(sentences []string, lang model.Language) ([]string, error) {
+	out, _ := c.Cached(sentences, lang)
+
+	missing := []string{}
+	c.mu.RLock()
+	for _, sentence := range sentences {
+		if _, ok := c.cache[cacheKey(sentence, lang)]; !ok {
+			missing = append(missing, sentence)
+		}
+	}
+	c.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	vocalized, err := c.diacritizer.Diacritize(missing, lang)
+	if err != nil {
+		return sentences, err
+	}
+
+	found := make(map[string]string, len(missing))
+	for i, sentence := range missing {
+		// Don't cache the sentences that couldn't be vocalized so that we retry them next time
+		if vocalized[i] != sentence {
+			found[sentence] = vocalized[i]
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out = make([]string, len(sentences))
+	for i, sentence := range sentences {
+		out[i] = sentence
+		if v, ok := c.cache[cacheKey(sentence, lang)]; ok {
+			out[i] = v
+		}
+		if v, ok := found[sentence]; ok {
+			out[i] = v
+		}
+	}
+
+	for _, sentence := range missing {
+		if v, ok := found[sentence]; ok {
+			c.add(cacheKey(sentence, lang), v)
+		}
+	}
+
+	return out, nil
+}
+
+// add caches a vocalized sentence and drops the oldest ones above the size of
+// the cache, c.mu must be locked.
+func (c *CachedDiacritizer) add(key string, vocalized string) {
+	if _, ok := c.cache[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.cache[key] = vocalized
+
+	for len(c.order) > c.size {
+		delete(c.cache, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// skeleton is the sentence without its diacritics, the hamza of the alef
+// being often added by the diacritizers.
+func skeleton(sentence string) string {
+	return strings.Join(strings.Fields(NormalizeAlef(Normalize(sentence))), " ")
+}
+
+// align matches the lines returned by a diacritizer with the input sentences.
+// Diacritizers sometimes merge, split, drop or rewrite lines so each sentence
+// is matched with the next output line having the same letters, the sentences
+// without a match are kept without diacritics.
+func align(sentences []string, output []string) []string {
+	lines := []string{}
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		// Skip the empty lines and the markdown code fences LLMs like to add
+		if line != "" && !strings.HasPrefix(line, "```") {
+			lines = append(lines, line)
+		}
+	}
+
+	out := make([]string, len(sentences))
+	next := 0
+	for i, sentence := range sentences {
+		out[i] = sentence
+		want := skeleton(sentence)
+		for j := next; j < len(lines); j++ {
+			if skeleton(lines[j]) == want {
+				out[i] = lines[j]
+				next = j + 1
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+// LLMDiacritizer vocalizes sentences using any OpenAI compatible chat
+// completion endpoint (e.g. a local llama.cpp or Ollama server).
+type LLMDiacritizer struct {
+	LLM *LLMClient
+}
+
+func (d *LLMDiacritizer) Diacritize(sentences []string, lang model.Language) ([]string, error) {
+	content, err := d.LLM.Chat([]ChatMessage{
+		{
+			Role: "system",
+			Content: "You add the full arabic diacritics (harakat) to the text you are given. " +
+				"Answer with the same lines in the same order, one sentence per line, without any other text or translation.",
+		},
+		{
+			Role:    "user",
+			Content: strings.Join(sentences, "\n"),
+		},
+	}, 0)
+	if err != nil {
+		return sentences, fmt.Errorf("failed to diacritize with %s: %w", d.LLM.Name, err)
+	}
+
+	return align(sentences, strings.Split(content, "\n")), nil
+}
@@ -0,0 +1,139 @@
+package clients
+
+import (
+	"reflect"
+	"sahib/model"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		name      string
+		sentences []string
+		output    []string
+		want      []string
+	}{
+		{
+			name:      "same lines",
+			sentences: []string{"ذهب الولد", "كتب"},
+			output:    []string{"ذَهَبَ الوَلَدُ", "كَتَبَ"},
+			want:      []string{"ذَهَبَ الوَلَدُ", "كَتَبَ"},
+		},
+		{
+			name:      "code fences and empty lines",
+			sentences: []string{"ذهب", "كتب"},
+			output:    []string{"```", "ذَهَبَ", "", "كَتَبَ", "```"},
+			want:      []string{"ذَهَبَ", "كَتَبَ"},
+		},
+		{
+			name:      "hamza added to the alef",
+			sentences: []string{"اكل الولد"},
+			output:    []string{"أَكَلَ الوَلَدُ"},
+			want:      []string{"أَكَلَ الوَلَدُ"},
+		},
+		{
+			name:      "dropped line",
+			sentences: []string{"ذهب", "كتب", "قرأ"},
+			output:    []string{"ذَهَبَ", "قَرَأَ"},
+			want:      []string{"ذَهَبَ", "كتب", "قَرَأَ"},
+		},
+		{
+			name:      "merged lines",
+			sentences: []string{"ذهب", "كتب"},
+			output:    []string{"ذَهَبَ كَتَبَ"},
+			want:      []string{"ذهب", "كتب"},
+		},
+		{
+			// Same number of lines, the rewritten one is not used
+			name:      "rewritten line",
+			sentences: []string{"ذهب الولد", "كتب"},
+			output:    []string{"ذَهَبَ الطِّفْلُ", "كَتَبَ"},
+			want:      []string{"ذهب الولد", "كَتَبَ"},
+		},
+		{
+			name:      "translated answer",
+			sentences: []string{"ذهب"},
+			output:    []string{"He went"},
+			want:      []string{"ذهب"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := align(test.sentences, test.output); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: align = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// fakeDiacritizer adds a fatha after the first letter and counts its calls.
+type fakeDiacritizer struct {
+	calls int
+}
+
+func (f *fakeDiacritizer) Diacritize(sentences []string, lang model.Language) ([]string, error) {
+	f.calls++
+	out := make([]string, len(sentences))
+	for i, s := range sentences {
+		runes := []rune(s)
+		out[i] = string(runes[0]) + "َ" + string(runes[1:])
+	}
+	return out, nil
+}
+
+func TestCachedDiacritizer(t *testing.T) {
+	fake := &fakeDiacritizer{}
+	c := NewCachedDiacritizer(fake)
+	lang := model.Language{Code: "en"}
+
+	if _, ok := c.Cached([]string{"كتب"}, lang); ok {
+		t.Error("nothing should be cached yet")
+	}
+
+	got, err := c.Diacritize([]string{"كتب", "ذهب"}, lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"كَتب", "ذَهب"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Diacritize = %q, want %q", got, want)
+	}
+
+	if got, ok := c.Cached([]string{"ذهب"}, lang); !ok || got[0] != "ذَهب" {
+		t.Errorf("Cached = %q, %v", got, ok)
+	}
+
+	if _, err := c.Diacritize([]string{"ذهب", "كتب"}, lang); err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 1 {
+		t.Errorf("the cached sentences were vocalized again (%d calls)", fake.calls)
+	}
+}
+
+func TestCachedDiacritizerSize(t *testing.T) {
+	fake := &fakeDiacritizer{}
+	c := NewCachedDiacritizer(fake)
+	c.size = 2
+	lang := model.Language{Code: "en"}
+
+	got, err := c.Diacritize([]string{"كتب", "ذهب", "قرأ"}, lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Vocalized even if they don't all fit in the cache
+	if want := []string{"كَتب", "ذَهب", "قَرأ"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Diacritize = %q, want %q", got, want)
+	}
+	if len(c.cache) != 2 || len(c.order) != 2 {
+		t.Errorf("cache has %d sentences and %d in order, want 2", len(c.cache), len(c.order))
+	}
+
+	if _, err := c.Diacritize([]string{"جلس"}, lang); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Cached([]string{"جلس"}, lang); !ok {
+		t.Error("the last sentence should be cached")
+	}
+	if len(c.cache) != 2 {
+		t.Errorf("cache has %d sentences, want 2", len(c.cache))
+	}
+}
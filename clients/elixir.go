@@ -2,8 +2,8 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
 	"mime/multipart"
 	"sahib/model"
 	"strings"
@@ -21,9 +21,28 @@ func QueryElixir(word string, ignored model.Language) (*model.Translations, erro
 
 	start := time.Now()
 	defer func() {
-		result.Elapsed = elapsed(start)
+		result.Elapsed = Elapsed(start)
 	}()
 
+	lexemes, err := resolveElixir(context.Background(), word)
+	if err != nil {
+		return result, err
+	}
+	result.List = lexemes
+
+	return result, nil
+}
+
+// Modes of the elixir web interface
+const (
+	ElixirResolve = "resolve"
+	ElixirInflect = "inflect"
+	ElixirDerive  = "derive"
+)
+
+// postElixir submits the text to elixir with the given mode and returns the
+// resulting page.
+func postElixir(ctx context.Context, mode string, text string) (*goquery.Document, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -31,10 +50,10 @@ func QueryElixir(word string, ignored model.Language) (*model.Translations, erro
 		key string
 		val string
 	}{
-		{"text", word},
+		{"text", text},
 		{"code", "Unicode"},
-		{"submit", "Resolve"},
-		{"mode", "resolve"},
+		{"submit", strings.ToUpper(mode[:1]) + mode[1:]},
+		{"mode", mode},
 		{".cgifields", "code"},
 		{".cgifields", "fuzzy"},
 		{".cgifields", "quick"},
@@ -43,40 +62,167 @@ func QueryElixir(word string, ignored model.Language) (*model.Translations, erro
 	for _, f := range formFields {
 		err := writer.WriteField(f.key, f.val)
 		if err != nil {
-			return result, fmt.Errorf("Couldn't write form field %s: %v\n", f.key, err)
+			return nil, fmt.Errorf("Couldn't write form field %s: %v\n", f.key, err)
 		}
 	}
 
 	err := writer.Close()
 	if err != nil {
-		return result, fmt.Errorf("Couldn't write form multipart %v\n", err)
+		return nil, fmt.Errorf("Couldn't write form multipart %v\n", err)
 	}
 
-	res, err := queryURL("POST", ElixirURL, body, map[string]string{ContentType: writer.FormDataContentType()}, false)
+	res, err := queryURLContext(ctx, "POST", ElixirURL, body, map[string]string{ContentType: writer.FormDataContentType()}, false)
 	if err != nil {
-		return result, fmt.Errorf("Failed to query elixir: %w", err)
+		return nil, fmt.Errorf("Failed to query elixir: %w", err)
 	}
 	defer res.Body.Close()
 
 	// Load the HTML document
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("Failed to parse elixir response: %w", err)
+	}
+
+	return doc, nil
+}
+
+// resolveElixir returns the lemmas elixir finds for the text with its resolve
+// mode, with their morphology.
+func resolveElixir(ctx context.Context, text string) ([]model.Translation, error) {
+	doc, err := postElixir(ctx, ElixirResolve, text)
+	if err != nil {
+		return nil, err
 	}
 
+	lexemes := []*model.Morphology{}
+	byLemma := map[string]*model.Morphology{}
 	// Find the words
 	doc.Find(".lexeme").Each(func(i int, s *goquery.Selection) {
-		// For each item found, get the title
-		tag := s.Find(".xtag").Text()
-		orth := s.Find(".orth").Text()
-		reflex := s.Find(".reflex").Text()
-
-		result.List = append(result.List, model.Translation{
-			Meta:        tag,
-			Arabic:      orth,
-			Translation: strings.ReplaceAll(reflex, "\"", ""),
+		m := &model.Morphology{
+			Lemma:   elixirText(s, ".orth"),
+			Root:    elixirText(s, ".root"),
+			Pattern: elixirText(s, ".morphs"),
+			Gloss:   strings.ReplaceAll(elixirText(s, ".reflex"), "\"", ""),
+			Tag:     elixirText(s, ".xtag"),
+		}
+		m.Features = DecodeTag(m.Tag)
+
+		// The inflected forms of the lemma found in the text, with their full tag
+		m.Forms = elixirForms(s)
+
+		// The same lemma is listed once per reading of the text
+		if other, ok := byLemma[m.Lemma]; ok {
+			other.Forms = append(other.Forms, m.Forms...)
+			if m.Gloss != "" && !strings.Contains(other.Gloss, m.Gloss) {
+				other.Gloss += "; " + m.Gloss
+			}
+			return
+		}
+		byLemma[m.Lemma] = m
+		lexemes = append(lexemes, m)
+	})
+
+	rows := make([]model.Translation, 0, len(lexemes))
+	for _, m := range lexemes {
+		rows = append(rows, model.Translation{
+			Arabic:      m.Lemma,
+			Translation: m.Gloss,
+			Meta:        morphologySummary(m),
+			Morphology:  m,
 		})
+	}
+
+	return rows, nil
+}
+
+func elixirText(s *goquery.Selection, selector string) string {
+	return strings.TrimSpace(s.Find(selector).First().Text())
+}
+
+// morphologySummary returns the part of speech, root and pattern of the lemma.
+func morphologySummary(m *model.Morphology) string {
+	parts := []string{}
+	if m.Features.POS != "" {
+		parts = append(parts, m.Features.POS)
+	} else if m.Tag != "" {
+		parts = append(parts, m.Tag)
+	}
+	if m.Root != "" {
+		parts = append(parts, "root "+m.Root)
+	}
+	if m.Pattern != "" {
+		parts = append(parts, "pattern "+m.Pattern)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// elixirForms returns the forms listed with their full tag in the selection.
+func elixirForms(s *goquery.Selection) []model.InflectedForm {
+	forms := []model.InflectedForm{}
+	s.Find(".tag").Each(func(j int, t *goquery.Selection) {
+		form := model.InflectedForm{Tag: strings.TrimSpace(t.Text())}
+		form.Features = DecodeTag(form.Tag)
+		row := t.Closest("tr")
+		form.Arabic = elixirText(row, ".form")
+		if form.Arabic == "" {
+			form.Arabic = elixirText(row, ".orth")
+		}
+		if form.Arabic != "" {
+			forms = append(forms, form)
+		}
 	})
+	return forms
+}
 
-	return result, nil
+// InflectElixir returns the full inflection paradigm of a lemma, grouped in
+// tables by part of speech, aspect, mood and voice.
+func InflectElixir(ctx context.Context, lemma string) ([]model.Paradigm, error) {
+	doc, err := postElixir(ctx, ElixirInflect, lemma)
+	if err != nil {
+		return nil, err
+	}
+
+	return paradigms(elixirForms(doc.Selection)), nil
+}
+
+// DeriveElixir returns the forms derived from the root of a lemma (verbal
+// nouns, participles...), grouped like the inflections.
+func DeriveElixir(ctx context.Context, lemma string) ([]model.Paradigm, error) {
+	doc, err := postElixir(ctx, ElixirDerive, lemma)
+	if err != nil {
+		return nil, err
+	}
+
+	return paradigms(elixirForms(doc.Selection)), nil
+}
+
+func paradigms(forms []model.InflectedForm) []model.Paradigm {
+	out := []model.Paradigm{}
+	index := map[string]int{}
+	for _, form := range forms {
+		f := form.Features
+		title := strings.Join(nonEmpty(f.POS, f.Aspect, f.Mood, f.Voice), ", ")
+		if title == "" {
+			title = "other"
+		}
+
+		i, ok := index[title]
+		if !ok {
+			i = len(out)
+			index[title] = i
+			out = append(out, model.Paradigm{Title: title})
+		}
+		out[i].Forms = append(out[i].Forms, form)
+	}
+	return out
+}
+
+func nonEmpty(values ...string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
@@ -0,0 +1,100 @@
+package clients
+
+import (
+	"sahib/model"
+	"strings"
+)
+
+// Values of the positions of the Elixir FM tags (the PADT tag set):
+// 1 part of speech, 2 its subcategory, 3 mood, 4 voice, 6 person, 7 gender,
+// 8 number, 9 case and 10 state. '-' means not applicable.
+var (
+	tagPOS = map[byte]string{
+		'N': "noun",
+		'A': "adjective",
+		'V': "verb",
+		'S': "pronoun",
+		'Q': "numeral",
+		'D': "adverb",
+		'P': "preposition",
+		'C': "conjunction",
+		'F': "particle",
+		'I': "interjection",
+		'Z': "proper noun",
+		'Y': "abbreviation",
+		'X': "foreign word",
+		'G': "symbol",
+	}
+	tagAspect = map[byte]string{
+		'P': "perfective",
+		'I': "imperfective",
+		'C': "imperative",
+	}
+	tagMood = map[byte]string{
+		'I': "indicative",
+		'S': "subjunctive",
+		'J': "jussive",
+		'E': "energic",
+		'D': "subjunctive or jussive",
+	}
+	tagVoice = map[byte]string{
+		'A': "active",
+		'P': "passive",
+	}
+	tagPerson = map[byte]string{
+		'1': "1st person",
+		'2': "2nd person",
+		'3': "3rd person",
+	}
+	tagGender = map[byte]string{
+		'M': "masculine",
+		'F': "feminine",
+	}
+	tagNumber = map[byte]string{
+		'S': "singular",
+		'D': "dual",
+		'P': "plural",
+	}
+	tagCase = map[byte]string{
+		'1': "nominative",
+		'2': "genitive",
+		'4': "accusative",
+	}
+	tagState = map[byte]string{
+		'I': "indefinite",
+		'D': "definite",
+		'R': "construct",
+		'A': "absolute",
+		'C': "complex",
+		'L': "lifted",
+	}
+)
+
+// DecodeTag returns the features of a positional Elixir FM tag, the unknown
+// or missing positions are left empty.
+func DecodeTag(tag string) model.TagFeatures {
+	tag = strings.TrimSpace(tag)
+	at := func(i int, values map[byte]string) string {
+		if i >= len(tag) {
+			return ""
+		}
+		return values[tag[i]]
+	}
+
+	f := model.TagFeatures{
+		POS:    at(0, tagPOS),
+		Mood:   at(2, tagMood),
+		Voice:  at(3, tagVoice),
+		Person: at(5, tagPerson),
+		Gender: at(6, tagGender),
+		Number: at(7, tagNumber),
+		Case:   at(8, tagCase),
+		State:  at(9, tagState),
+	}
+	// The subcategory is only meaningful as an aspect for the verbs
+	if len(tag) > 0 && tag[0] == 'V' {
+		f.Aspect = at(1, tagAspect)
+	}
+
+	return f
+}
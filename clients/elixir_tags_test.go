@@ -0,0 +1,62 @@
+package clients
+
+import (
+	"sahib/model"
+	"testing"
+)
+
+func TestDecodeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want model.TagFeatures
+	}{
+		{
+			tag: "VIIA-3MS--",
+			want: model.TagFeatures{POS: "verb", Aspect: "imperfective", Mood: "indicative", Voice: "active",
+				Person: "3rd person", Gender: "masculine", Number: "singular"},
+		},
+		{
+			tag:  "VP-P-3FD--",
+			want: model.TagFeatures{POS: "verb", Aspect: "perfective", Voice: "passive", Person: "3rd person", Gender: "feminine", Number: "dual"},
+		},
+		{
+			tag:  "N------S1D",
+			want: model.TagFeatures{POS: "noun", Number: "singular", Case: "nominative", State: "definite"},
+		},
+		// The subcategory of the other parts of speech isn't an aspect
+		{
+			tag:  "AP-----P4I",
+			want: model.TagFeatures{POS: "adjective", Number: "plural", Case: "accusative", State: "indefinite"},
+		},
+		{
+			tag:  "  P---------  ",
+			want: model.TagFeatures{POS: "preposition"},
+		},
+		{
+			tag:  "N",
+			want: model.TagFeatures{POS: "noun"},
+		},
+		{
+			tag:  "",
+			want: model.TagFeatures{},
+		},
+		{
+			tag:  "??????????",
+			want: model.TagFeatures{},
+		},
+	}
+
+	for _, test := range tests {
+		if got := DecodeTag(test.tag); got != test.want {
+			t.Errorf("DecodeTag(%q) = %+v, want %+v", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestTagFeaturesString(t *testing.T) {
+	f := DecodeTag("VCJA-2FP--")
+	want := "verb, imperative, jussive, active, 2nd person, feminine, plural"
+	if got := f.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
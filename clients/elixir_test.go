@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParadigms(t *testing.T) {
+	f, err := os.Open("testdata/elixir_inflect.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]string{}
+	titles := []string{}
+	for _, p := range paradigms(elixirForms(doc.Selection)) {
+		titles = append(titles, p.Title)
+		for _, form := range p.Forms {
+			got[p.Title] = append(got[p.Title], form.Arabic)
+		}
+	}
+
+	wantTitles := []string{
+		"verb, perfective, active",
+		"verb, imperfective, indicative, active",
+		"verb, imperfective, subjunctive, active",
+		"verb, perfective, passive",
+		"verb, imperative, active",
+		"noun",
+		"other",
+	}
+	if !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("titles = %q, want %q", titles, wantTitles)
+	}
+
+	want := map[string][]string{
+		"verb, perfective, active":                {"كَتَبَ", "كَتَبَتْ"},
+		"verb, imperfective, indicative, active":  {"يَكْتُبُ", "تَكْتُبُ"},
+		"verb, imperfective, subjunctive, active": {"يَكْتُبَ"},
+		"verb, perfective, passive":               {"كُتِبَ"},
+		// The form without arabic is skipped
+		"verb, imperative, active": {"اُكْتُبْ"},
+		"noun":                     {"الكِتَابَةُ", "كِتَابَةٍ"},
+		// Without form the orthography of the row is used
+		"other": {"كِتَابَات"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("forms = %q, want %q", got, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sahib/model"
 	"strings"
+	"sync"
 	"unicode"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,6 +14,14 @@ import (
 type HansWehr struct {
 	db    *sql.DB
 	forms map[string]verbForm
+	// The words of the dictionary by their form without the hamza variants of
+	// alef, kept in memory since the database is used as is
+	words map[string][]hansWehrWord
+}
+
+type hansWehrWord struct {
+	id   int64
+	word string
 }
 
 func NewHansWehrClient(path string) (*HansWehr, error) {
@@ -101,7 +110,33 @@ func NewHansWehrClient(path string) (*HansWehr, error) {
 		formsMap[f.key] = f
 	}
 
-	return &HansWehr{db: db, forms: formsMap}, nil
+	words, err := loadHansWehrWords(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Failed to load the hans wehr words: %w", err)
+	}
+
+	return &HansWehr{db: db, forms: formsMap, words: words}, nil
+}
+
+func loadHansWehrWords(db *sql.DB) (map[string][]hansWehrWord, error) {
+	rows, err := db.Query(`SELECT id, word FROM DICTIONARY WHERE word IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := map[string][]hansWehrWord{}
+	for rows.Next() {
+		w := hansWehrWord{}
+		if err := rows.Scan(&w.id, &w.word); err != nil {
+			return nil, err
+		}
+		key := NormalizeAlef(w.word)
+		words[key] = append(words[key], w)
+	}
+
+	return words, rows.Err()
 }
 
 func removeDiacritics(input string) string {
@@ -115,6 +150,99 @@ func removeDiacritics(input string) string {
 }
 
 func (h *HansWehr) Query(word string) (*model.Definitions, error) {
+    // Remove the diacritics since everything is stored without diacritics in the DB
+	return h.query("d1.word = ?", removeDiacritics(word))
+}
+
+// Lookup queries the dictionary for a word as found in a text: if the word itself
+// isn't found, it tries again after stripping its clitics and finally ignoring
+// the hamza variants of alef. The form that matched is returned with the definitions.
+func (h *HansWehr) Lookup(word string) (*model.Definitions, string, error) {
+	candidates := Candidates(word)
+	for _, exact := range []bool{true, false} {
+		for _, candidate := range candidates {
+			ids := h.ids(candidate, exact)
+			if len(ids) == 0 {
+				continue
+			}
+			defs, err := h.query("d1.id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", ids...)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(defs.Definitions) > 0 {
+				return defs, candidate, nil
+			}
+		}
+	}
+
+	return &model.Definitions{}, "", nil
+}
+
+// ids returns the ids of the entries of a word, or of the words only differing
+// by the hamza of their alef if not exact.
+func (h *HansWehr) ids(word string, exact bool) []any {
+	ids := []any{}
+	for _, w := range h.words[NormalizeAlef(word)] {
+		if !exact || w.word == word {
+			ids = append(ids, w.id)
+		}
+	}
+	return ids
+}
+
+// LookupResult is the definitions of a word of a text and the form that matched.
+type LookupResult struct {
+	Definitions *model.Definitions
+	Match       string
+}
+
+// Number of words looked up at the same time by LookupAll
+const lookupWorkers = 8
+
+// LookupAll looks up the distinct words of a text concurrently, the results
+// are keyed by word.
+func (h *HansWehr) LookupAll(words []string) (map[string]LookupResult, error) {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			unique = append(unique, w)
+		}
+	}
+
+	results := make(map[string]LookupResult, len(unique))
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lookupWorkers)
+	for _, word := range unique {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			defs, match, err := h.Lookup(word)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to look up %s: %w", word, err)
+				}
+				return
+			}
+			results[word] = LookupResult{Definitions: defs, Match: match}
+		}()
+	}
+	wg.Wait()
+
+	return results, firstErr
+}
+
+func (h *HansWehr) query(condition string, args ...any) (*model.Definitions, error) {
 
 	q := `
 SELECT
@@ -124,11 +252,10 @@ FROM
     INNER JOIN
     DICTIONARY d2
     ON d2.id = d1.parent_id
-    WHERE d1.word = ?
+    WHERE ` + condition + `
     LIMIT 10
     `
-    // Remove the diacritics since everything is stored without diacritics in the DB
-	rows, err := h.db.Query(q, removeDiacritics(word))
+	rows, err := h.db.Query(q, args...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query word in sqlite db: %w", err)
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// newTestHansWehr creates a dictionary with a root and its words, like the
+// one of the hans wehr database.
+func newTestHansWehr(t *testing.T) *HansWehr {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hanswehr.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`CREATE TABLE DICTIONARY (id INTEGER PRIMARY KEY, word TEXT, definition TEXT, parent_id INTEGER, quran_occurrence INTEGER)`,
+		`INSERT INTO DICTIONARY VALUES (1, 'أمن', 'to be safe', 1, 0)`,
+		`INSERT INTO DICTIONARY VALUES (2, 'إيمان', 'faith', 1, 45)`,
+		`INSERT INTO DICTIONARY VALUES (3, 'أمين', 'faithful', 1, 14)`,
+		`INSERT INTO DICTIONARY VALUES (4, 'كتب', 'to write', 4, 0)`,
+		`INSERT INTO DICTIONARY VALUES (5, 'كتاب', 'book', 4, 230)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := NewHansWehrClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestHansWehrLookup(t *testing.T) {
+	h := newTestHansWehr(t)
+
+	tests := []struct {
+		word  string
+		match string
+		def   string
+	}{
+		{"كتاب", "كتاب", "book"},
+		{"والكتاب", "كتاب", "book"},
+		// Found without the hamza of the alef
+		{"ايمان", "ايمان", "faith"},
+		{"بالايمان", "ايمان", "faith"},
+		{"أمين", "أمين", "faithful"},
+		{"قلم", "", ""},
+	}
+
+	for _, test := range tests {
+		defs, match, err := h.Lookup(test.word)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %s", test.word, err)
+		}
+		if match != test.match {
+			t.Errorf("Lookup(%q) matched %q, want %q", test.word, match, test.match)
+		}
+		if test.def == "" {
+			if len(defs.Definitions) > 0 {
+				t.Errorf("Lookup(%q) = %+v, want no definition", test.word, defs.Definitions)
+			}
+			continue
+		}
+		if len(defs.Definitions) == 0 || defs.Definitions[0].Definition != test.def {
+			t.Errorf("Lookup(%q) = %+v, want %q", test.word, defs.Definitions, test.def)
+		}
+	}
+}
+
+func TestHansWehrLookupAll(t *testing.T) {
+	h := newTestHansWehr(t)
+
+	results, err := h.LookupAll([]string{"كتاب", "ايمان", "كتاب", "قلم"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("LookupAll returned %d results, want one per distinct word", len(results))
+	}
+	if results["كتاب"].Match != "كتاب" || results["ايمان"].Match != "ايمان" || results["قلم"].Match != "" {
+		t.Errorf("unexpected lookups: %+v", results)
+	}
+}
+
+func TestHansWehrKeepsDatabase(t *testing.T) {
+	h := newTestHansWehr(t)
+
+	var columns, indexes int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('DICTIONARY')`).Scan(&columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index'`).Scan(&indexes); err != nil {
+		t.Fatal(err)
+	}
+	if columns != 5 || indexes != 0 {
+		t.Errorf("the dictionary has %d columns and %d indexes, want it unchanged", columns, indexes)
+	}
+}
@@ -0,0 +1,147 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+	"sahib/model"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Lane queries a classical dictionary (Lane's Lexicon, Lisan al-Arab...)
+// imported with the ImportLane functions. Like Hans Wehr, the entries point
+// to their root with their parent_id.
+type Lane struct {
+	db *sql.DB
+}
+
+func NewLaneClient(path string) (*Lane, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to init lane client: %w", err)
+	}
+
+	return &Lane{db: db}, nil
+}
+
+func (l *Lane) Close() error {
+	return l.db.Close()
+}
+
+// maxLaneRoots is the number of roots shown for a word.
+const maxLaneRoots = 3
+
+// Query returns the roots of the word with all their entries, the word being
+// looked up without its clitics if it isn't found as is.
+func (l *Lane) Query(word string, ignored model.Language) (*model.Translations, error) {
+	result := &model.Translations{}
+	start := time.Now()
+	defer func() {
+		result.Elapsed = Elapsed(start)
+	}()
+
+	var roots []int64
+	var key string
+	for _, candidate := range Candidates(word) {
+		key = normalizeKey(candidate)
+		var err error
+		roots, err = l.rootsOf(key)
+		if err != nil {
+			return result, err
+		}
+		if len(roots) > 0 {
+			break
+		}
+	}
+
+	for _, id := range roots {
+		root, err := l.root(id, key)
+		if err != nil {
+			return result, err
+		}
+
+		result.Classical = append(result.Classical, *root)
+		for _, e := range root.Entries {
+			result.List = append(result.List, model.Translation{
+				Arabic:      e.Word,
+				Translation: shorten(e.Definition, 300),
+				Meta:        e.PageReference(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// rootsOf returns the ids of the roots of the entries, or roots, matching the key.
+func (l *Lane) rootsOf(key string) ([]int64, error) {
+	rows, err := l.db.Query(`SELECT DISTINCT COALESCE(parent_id, id) FROM lane WHERE normalized = ? LIMIT ?`, key, maxLaneRoots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lane: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error while scanning lane root: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (l *Lane) root(id int64, key string) (*model.ClassicalRoot, error) {
+	root := &model.ClassicalRoot{}
+	err := l.db.QueryRow(`SELECT word, volume, page FROM lane WHERE id = ?`, id).Scan(&root.Root, &root.Volume, &root.Page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lane root %d: %w", id, err)
+	}
+
+	rows, err := l.db.Query(`SELECT word, normalized, definition, volume, page FROM lane WHERE parent_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the entries of %s: %w", root.Root, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := model.ClassicalEntry{}
+		var normalized string
+		if err := rows.Scan(&e.Word, &normalized, &e.Definition, &e.Volume, &e.Page); err != nil {
+			return nil, fmt.Errorf("error while scanning lane entry: %w", err)
+		}
+		e.Matched = normalized == key
+		root.Entries = append(root.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Neighbour roots, in the order of the dictionary
+	for _, q := range []struct {
+		dest  *string
+		query string
+	}{
+		{&root.Previous, `SELECT word FROM lane WHERE parent_id IS NULL AND id < ? ORDER BY id DESC LIMIT 1`},
+		{&root.Next, `SELECT word FROM lane WHERE parent_id IS NULL AND id > ? ORDER BY id LIMIT 1`},
+	} {
+		err := l.db.QueryRow(q.query, id).Scan(q.dest)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get the roots around %s: %w", root.Root, err)
+		}
+	}
+
+	return root, nil
+}
+
+// shorten cuts the text after max characters, unlike truncate it doesn't
+// split the arabic letters.
+func shorten(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
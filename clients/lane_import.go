@@ -0,0 +1,282 @@
+package clients
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LaneFile is a dictionary file to import, its format is given by the
+// extension of its name: a TEI file of Lane's Lexicon (.xml) or a tab
+// separated file (.tsv).
+type LaneFile struct {
+	Name   string
+	Reader io.Reader
+}
+
+// ImportLane imports the dictionary files in order into the lane table of the
+// database, which is recreated unless the files are appended to the imported
+// ones. The volume is the one of the xml files whose page breaks don't have
+// one. It returns the number of imported entries.
+func ImportLane(db *sql.DB, files []LaneFile, volume int, appendFiles bool) (int, error) {
+	schema := []string{
+		`DROP TABLE IF EXISTS lane`,
+		`CREATE TABLE lane (
+            id INTEGER PRIMARY KEY,
+            parent_id INTEGER REFERENCES lane(id),
+            word TEXT NOT NULL,
+            normalized TEXT NOT NULL,
+            definition TEXT NOT NULL,
+            volume INTEGER NOT NULL,
+            page INTEGER NOT NULL
+        )`,
+		`CREATE INDEX lane_normalized ON lane(normalized)`,
+		`CREATE INDEX lane_parent ON lane(parent_id)`,
+	}
+
+	// The previous entries are kept if the import of any file fails
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if !appendFiles {
+		for _, stmt := range schema {
+			if _, err := tx.Exec(stmt); err != nil {
+				return 0, fmt.Errorf("failed to create the lane table: %w", err)
+			}
+		}
+	}
+
+	imp, err := newLaneImport(tx)
+	if err != nil {
+		return 0, err
+	}
+	defer imp.insert.Close()
+
+	for _, f := range files {
+		before := imp.count
+		switch strings.ToLower(filepath.Ext(f.Name)) {
+		case ".xml":
+			err = imp.readXML(f.Reader, volume)
+		case ".tsv":
+			err = imp.readTSV(f.Reader)
+		default:
+			err = fmt.Errorf("unsupported file")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %s: %w", f.Name, err)
+		}
+		log.Printf("Imported %d entries from %s", imp.count-before, f.Name)
+	}
+
+	return imp.count, tx.Commit()
+}
+
+// laneImport inserts the entries of the files under their root, the root
+// rows being created the first time they are seen.
+type laneImport struct {
+	tx     *sql.Tx
+	insert *sql.Stmt
+	roots  map[string]int64
+	count  int
+}
+
+func newLaneImport(tx *sql.Tx) (*laneImport, error) {
+	insert, err := tx.Prepare(`INSERT INTO lane (parent_id, word, normalized, definition, volume, page) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &laneImport{tx: tx, insert: insert, roots: map[string]int64{}}, nil
+}
+
+func (l *laneImport) rootID(root string, volume int, page int) (int64, error) {
+	key := normalizeKey(root)
+	if id, ok := l.roots[key]; ok {
+		return id, nil
+	}
+
+	// The root may come from a previous import
+	var id int64
+	err := l.tx.QueryRow(`SELECT id FROM lane WHERE parent_id IS NULL AND normalized = ?`, key).Scan(&id)
+	if err == sql.ErrNoRows {
+		res, ierr := l.insert.Exec(nil, root, key, "", volume, page)
+		if ierr != nil {
+			return 0, fmt.Errorf("failed to insert root %s: %w", root, ierr)
+		}
+		id, err = res.LastInsertId()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	l.roots[key] = id
+	return id, nil
+}
+
+func (l *laneImport) add(root string, word string, definition string, volume int, page int) error {
+	if root == "" || word == "" {
+		return nil
+	}
+
+	parent, err := l.rootID(root, volume, page)
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.insert.Exec(parent, word, normalizeKey(word), definition, volume, page); err != nil {
+		return fmt.Errorf("failed to insert %s: %w", word, err)
+	}
+	l.count++
+	return nil
+}
+
+func xmlAttr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// readXML imports a TEI file of Lane's Lexicon (one per letter, in arabic
+// script): the <entryFree> of each <div2 type="root"> are the entries of the
+// root and the <pb n="..."/> milestones give their page. The volume is the
+// one of the file unless the page breaks have a vol attribute.
+func (l *laneImport) readXML(r io.Reader, volume int) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	root, page := "", 0
+	pageBreak := func(start xml.StartElement) {
+		if n, err := strconv.Atoi(xmlAttr(start, "n")); err == nil {
+			page = n
+		}
+		if v, err := strconv.Atoi(xmlAttr(start, "vol")); err == nil {
+			volume = v
+		}
+	}
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid lane file: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "div2":
+			// The entries of a div2 never belong to the root of the previous one
+			root = ""
+			if xmlAttr(start, "type") == "root" {
+				root = strings.TrimSpace(xmlAttr(start, "n"))
+			}
+		case "pb":
+			pageBreak(start)
+		case "entryFree":
+			entryVolume, entryPage := volume, page
+			word, definition, err := readLaneEntry(decoder, pageBreak)
+			if err != nil {
+				return err
+			}
+			if w := strings.TrimSpace(xmlAttr(start, "key")); w != "" {
+				word = w
+			}
+			// Roots without attribute are named after their first entry
+			if root == "" {
+				root = word
+			}
+			if err := l.add(root, word, definition, entryVolume, entryPage); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readLaneEntry reads an <entryFree> until its end and returns its first
+// <orth> and its text.
+func readLaneEntry(decoder *xml.Decoder, pageBreak func(xml.StartElement)) (string, string, error) {
+	var orth, text strings.Builder
+	depth, inOrth, orthDone := 0, false, false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", "", fmt.Errorf("invalid lane entry: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Local == "pb" {
+				pageBreak(t)
+			}
+			if t.Name.Local == "orth" && !orthDone {
+				inOrth = true
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return strings.TrimSpace(orth.String()), strings.Join(strings.Fields(text.String()), " "), nil
+			}
+			depth--
+			if t.Name.Local == "orth" && inOrth {
+				inOrth, orthDone = false, true
+			}
+		case xml.CharData:
+			if inOrth {
+				orth.Write(t)
+			}
+			text.Write(t)
+		}
+	}
+}
+
+// readTSV imports a tab separated file of root, word, volume, page and
+// definition columns, e.g. an export of Lisan al-Arab, with an optional
+// header. The volume and page can be empty.
+func (l *laneImport) readTSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid entry at line %d: %w", line, err)
+		}
+		if len(record) < 5 || (line == 1 && strings.EqualFold(record[0], "root")) {
+			continue
+		}
+
+		volume, _ := strconv.Atoi(strings.TrimSpace(record[2]))
+		page, _ := strconv.Atoi(strings.TrimSpace(record[3]))
+		err = l.add(strings.TrimSpace(record[0]), strings.TrimSpace(record[1]), strings.TrimSpace(record[4]), volume, page)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package clients
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+const testLaneXML = `<?xml version="1.0" encoding="UTF-8"?>
+<TEI.2><text><body>
+<div1 type="letter" n="ك">
+<div2 type="root" n="كتب">
+<pb n="2589"/>
+<entryFree id="n1" key="كتب"><orth>كَتَبَ</orth>, aor. <orth>يَكْتُبُ</orth>, He wrote.</entryFree>
+<entryFree id="n2" key="كتاب"><orth>كِتَابٌ</orth> A book;
+<pb n="2590" vol="7"/> a writing.</entryFree>
+</div2>
+<div2 type="section">
+<entryFree id="n3"><orth>كَتَمَ</orth> He concealed.</entryFree>
+<entryFree id="n4"><orth>كِتْمَانٌ</orth> Concealment.</entryFree>
+</div2>
+</div1>
+</body></text></TEI.2>`
+
+const testLaneTSV = "root\tword\tvolume\tpage\tdefinition\n" +
+	"كتب\tمكتبة\t\t\tA library.\n" +
+	"قلم\tقلم\t8\t2986\tA pen.\n" +
+	"short\tline\n"
+
+func newTestLane(t *testing.T) *Lane {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "lane.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	files := []LaneFile{{"kaf.xml", strings.NewReader(testLaneXML)}, {"lisan.tsv", strings.NewReader(testLaneTSV)}}
+	if count, err := ImportLane(db, files, 6, false); err != nil || count != 6 {
+		t.Fatalf("ImportLane() = %d, %v, want 6 entries", count, err)
+	}
+
+	l, err := NewLaneClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestLaneQuery(t *testing.T) {
+	l := newTestLane(t)
+
+	tests := []struct {
+		word string
+		want []model.Translation
+	}{
+		{
+			word: "كتاب",
+			want: []model.Translation{
+				{Arabic: "كتب", Translation: "كَتَبَ, aor. يَكْتُبُ, He wrote.", Meta: "vol. 6 p. 2589"},
+				{Arabic: "كتاب", Translation: "كِتَابٌ A book; a writing.", Meta: "vol. 6 p. 2589"},
+				{Arabic: "مكتبة", Translation: "A library."},
+			},
+		},
+		// The entries of the second div2 have their own root
+		{
+			word: "كتمان",
+			want: []model.Translation{
+				{Arabic: "كَتَمَ", Translation: "كَتَمَ He concealed.", Meta: "vol. 7 p. 2590"},
+				{Arabic: "كِتْمَانٌ", Translation: "كِتْمَانٌ Concealment.", Meta: "vol. 7 p. 2590"},
+			},
+		},
+		// Found without its clitics
+		{
+			word: "والقلم",
+			want: []model.Translation{{Arabic: "قلم", Translation: "A pen.", Meta: "vol. 8 p. 2986"}},
+		},
+		{word: "ذهب"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.word, func(t *testing.T) {
+			res, err := l.Query(test.word, testLanguage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.List, test.want) {
+				t.Errorf("Query(%q) = %+v, want %+v", test.word, res.List, test.want)
+			}
+		})
+	}
+}
+
+func TestLaneRoot(t *testing.T) {
+	l := newTestLane(t)
+
+	res, err := l.Query("كتاب", testLanguage)
+	if err != nil || len(res.Classical) != 1 {
+		t.Fatalf("Query() = %+v, %v", res, err)
+	}
+
+	root := res.Classical[0]
+	if root.Root != "كتب" || root.PageReference() != "vol. 6 p. 2589" {
+		t.Errorf("root %s (%s)", root.Root, root.PageReference())
+	}
+	if root.Previous != "" || root.Next != "كَتَمَ" {
+		t.Errorf("previous %q, next %q", root.Previous, root.Next)
+	}
+
+	matched := []bool{}
+	for _, e := range root.Entries {
+		matched = append(matched, e.Matched)
+	}
+	if want := []bool{false, true, false}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("matched entries = %v, want %v", matched, want)
+	}
+}
+
+func TestLaneFailedImport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lane.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := ImportLane(db, []LaneFile{{"kaf.xml", strings.NewReader(testLaneXML)}}, 6, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first file is valid, the lexicon is replaced or appended to only
+	// once all of them are imported
+	truncated := `<TEI.2><div2 type="root" n="قلم"><entryFree><orth>قَلَمٌ</orth> A pen.`
+	for _, appendFiles := range []bool{false, true} {
+		files := []LaneFile{{"lisan.tsv", strings.NewReader(testLaneTSV)}, {"qaf.xml", strings.NewReader(truncated)}}
+		if _, err := ImportLane(db, files, 8, appendFiles); err == nil {
+			t.Errorf("append %v: expected an error for the truncated file", appendFiles)
+		}
+	}
+	if _, err := ImportLane(db, []LaneFile{{"lisan.txt", strings.NewReader(testLaneTSV)}}, 0, true); err == nil {
+		t.Error("expected an error for the unknown format")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM lane WHERE parent_id IS NOT NULL`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("%d entries after the failed imports, want the 4 of the first one", count)
+	}
+}
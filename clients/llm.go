@@ -0,0 +1,357 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sahib/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LLMClient talks to any OpenAI compatible chat/completions endpoint
+// (Perplexity, OpenAI, a local llama.cpp or Ollama server...).
+type LLMClient struct {
+	// Name of the provider, used in the logs and errors
+	Name string
+	// Key of the preset the client was built from, empty for custom
+	// endpoints. The server keys are looked up with it.
+	Preset      string
+	BaseURL     string
+	Model       string
+	ApiKey      string
+	Temperature float64
+	MaxTokens   int
+	// Whether the provider supports the json_schema response format
+	JSONSchema bool
+	// Provider specific fields added to every request
+	Extra map[string]interface{}
+	// Whether the provider only sends the usage of streamed answers when asked to
+	StreamUsage bool
+	// Records the tokens consumed by the requests, can be nil
+	Usage UsageRecorder
+	// Name of the user the usage is recorded for
+	User string
+}
+
+// LLMPresets returns the known providers, their fields are used as defaults
+// and can be overridden.
+func LLMPresets() map[string]LLMClient {
+	presets := map[string]LLMClient{
+		"perplexity": {
+			Name:        "Perplexity",
+			BaseURL:     "https://api.perplexity.ai",
+			Model:       "sonar-pro",
+			Temperature: 0.2,
+			MaxTokens:   1000,
+			JSONSchema:  true,
+			Extra: map[string]interface{}{
+				"top_p":                    0.9,
+				"search_domain_filter":     []string{"perplexity.ai"},
+				"return_images":            false,
+				"return_related_questions": false,
+				"search_recency_filter":    "month",
+				"top_k":                    0,
+				"presence_penalty":         0,
+				"frequency_penalty":        1,
+			},
+		},
+		"openai": {
+			Name:        "OpenAI",
+			BaseURL:     "https://api.openai.com/v1",
+			Model:       "gpt-4o-mini",
+			Temperature: 0.2,
+			MaxTokens:   1000,
+			JSONSchema:  true,
+			StreamUsage: true,
+		},
+		"ollama": {
+			Name:        "Ollama",
+			BaseURL:     "http://localhost:11434/v1",
+			Temperature: 0.2,
+			MaxTokens:   1000,
+			JSONSchema:  true,
+			StreamUsage: true,
+		},
+		"llamacpp": {
+			Name:        "llama.cpp",
+			BaseURL:     "http://localhost:8080/v1",
+			Temperature: 0.2,
+			MaxTokens:   1000,
+			JSONSchema:  true,
+		},
+	}
+
+	for key, preset := range presets {
+		preset.Preset = key
+		presets[key] = preset
+	}
+	return presets
+}
+
+// WithUsage returns a copy of the client recording its usage for the user.
+func (c *LLMClient) WithUsage(usage UsageRecorder, user string) *LLMClient {
+	client := *c
+	client.Usage = usage
+	client.User = user
+	return &client
+}
+
+// Perplexity returns a client for the perplexity API.
+func Perplexity(apiKey string) *LLMClient {
+	client := LLMPresets()["perplexity"]
+	client.ApiKey = apiKey
+	return &client
+}
+
+// LLMFromEnv builds a client from the <prefix>_PRESET, <prefix>_URL, <prefix>_MODEL,
+// <prefix>_API_KEY, <prefix>_TEMPERATURE, <prefix>_MAX_TOKENS and <prefix>_JSON_SCHEMA
+// environment variables.
+// It returns nil if neither a preset nor an url is set.
+func LLMFromEnv(prefix string) (*LLMClient, error) {
+	env := func(name string) string {
+		return os.Getenv(prefix + "_" + name)
+	}
+
+	client := LLMClient{Name: "LLM", Temperature: 0.2, MaxTokens: 1000}
+	if name := env("PRESET"); name != "" {
+		preset, ok := LLMPresets()[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown LLM preset %s for %s_PRESET", name, prefix)
+		}
+		client = preset
+	} else if env("URL") == "" {
+		return nil, nil
+	}
+
+	if v := env("URL"); v != "" {
+		client.BaseURL = v
+	}
+	if v := env("MODEL"); v != "" {
+		client.Model = v
+	}
+	if v := env("API_KEY"); v != "" {
+		client.ApiKey = v
+	}
+	if v := env("TEMPERATURE"); v != "" {
+		temperature, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_TEMPERATURE: %w", prefix, err)
+		}
+		client.Temperature = temperature
+	}
+	if v := env("MAX_TOKENS"); v != "" {
+		maxTokens, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_MAX_TOKENS: %w", prefix, err)
+		}
+		client.MaxTokens = maxTokens
+	}
+	if v := env("JSON_SCHEMA"); v != "" {
+		jsonSchema, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_JSON_SCHEMA: %w", prefix, err)
+		}
+		client.JSONSchema = jsonSchema
+	}
+
+	return &client, nil
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+// Chat sends the messages and returns the content of the first choice.
+func (c *LLMClient) Chat(messages []ChatMessage, temperature float64) (string, error) {
+	return c.chat(context.Background(), messages, temperature, nil)
+}
+
+// responseFormat returns the request fields asking for an answer matching the
+// schema, if the provider supports it.
+func (c *LLMClient) responseFormat(schema Schema) map[string]interface{} {
+	if !c.JSONSchema {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"response_format": map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   schema.Name,
+				"schema": schema.Definition,
+			},
+		},
+	}
+}
+
+// ChatJSON asks for an answer matching the schema and decodes it in out. If
+// the answer is invalid the model is asked once to fix it.
+func (c *LLMClient) ChatJSON(messages []ChatMessage, schema Schema, out interface{}) error {
+	extra := c.responseFormat(schema)
+	content, err := c.chat(context.Background(), messages, c.Temperature, extra)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s response: %s", c.Name, content)
+
+	return c.decodeOrRepair(context.Background(), messages, content, schema, out)
+}
+
+// decodeOrRepair decodes the answer of the model, if it is invalid the model
+// is asked once to fix it.
+func (c *LLMClient) decodeOrRepair(ctx context.Context, messages []ChatMessage, content string, schema Schema, out interface{}) error {
+	err := decodeJSON(content, schema, out)
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("Invalid %s response, asking to repair it: %s", c.Name, err)
+	messages = append(messages,
+		ChatMessage{Role: "assistant", Content: content},
+		ChatMessage{
+			Role:    "user",
+			Content: fmt.Sprintf("Your answer is invalid: %s. Answer again with only the fixed JSON object, in the requested format.", err),
+		},
+	)
+
+	content, err = c.chat(ctx, messages, c.Temperature, c.responseFormat(schema))
+	if err != nil {
+		return err
+	}
+	log.Printf("%s repaired response: %s", c.Name, content)
+
+	if err := decodeJSON(content, schema, out); err != nil {
+		return fmt.Errorf("%s answer doesn't have the expected format, even after asking to fix it: %w", c.Name, err)
+	}
+
+	return nil
+}
+
+func (c *LLMClient) post(ctx context.Context, messages []ChatMessage, temperature float64, extra map[string]interface{}, stream bool) (*http.Response, error) {
+	// A search makes several requests, the budget may be exceeded in between
+	if c.Usage != nil {
+		if err := c.Usage.CheckBudget(c.User); err != nil {
+			return nil, err
+		}
+	}
+
+	request := map[string]interface{}{}
+	for k, v := range c.Extra {
+		request[k] = v
+	}
+	for k, v := range extra {
+		request[k] = v
+	}
+	request["model"] = c.Model
+	request["messages"] = messages
+	request["temperature"] = temperature
+	request["stream"] = stream
+	if c.MaxTokens > 0 {
+		request["max_tokens"] = c.MaxTokens
+	}
+
+	jsonBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("Error serializing request body: %w\n", err)
+	}
+
+	headers := map[string]string{ContentType: "application/json"}
+	if c.ApiKey != "" {
+		headers["Authorization"] = "Bearer " + c.ApiKey
+	}
+
+	url := strings.TrimSuffix(c.BaseURL, "/") + "/chat/completions"
+	rawResp, err := queryURLContext(ctx, "POST", url, bytes.NewBuffer(jsonBody), headers, false)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending %s request: %w\n", c.Name, err)
+	}
+
+	return rawResp, nil
+}
+
+func (c *LLMClient) chat(ctx context.Context, messages []ChatMessage, temperature float64, extra map[string]interface{}) (string, error) {
+	rawResp, err := c.post(ctx, messages, temperature, extra, false)
+	if err != nil {
+		return "", err
+	}
+	defer rawResp.Body.Close()
+
+	body, err := io.ReadAll(rawResp.Body)
+	if err != nil {
+		return "", fmt.Errorf("Error reading body: %w\n", err)
+	}
+
+	resp := chatResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("Error deserializing api response: %w\n", err)
+	}
+	c.recordUsage(resp.Usage)
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("Empty response received from %s: %s\n", c.Name, truncate(string(body), 256))
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+func promptMessages(mode PromptMode, word string, lang model.Language) ([]ChatMessage, error) {
+	prompt, err := mode.Prompt(word, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ChatMessage{
+		{
+			Role:    "system",
+			Content: "Don't repeat yourself, be precise and concise.",
+		},
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}, nil
+}
+
+// Query asks the model for a translation of the word and example sentences.
+func (c *LLMClient) Query(word string, lang model.Language) (*model.Translations, error) {
+	mode, _ := FindPromptMode(PromptExamples)
+	return c.QueryPrompt(mode, word, lang)
+}
+
+// QueryPrompt asks the model the question of the prompt mode about the word.
+func (c *LLMClient) QueryPrompt(mode PromptMode, word string, lang model.Language) (*model.Translations, error) {
+	result := &model.Translations{}
+	answer := modeAnswer{mode: mode}
+
+	start := time.Now()
+	defer func() {
+		result.Elapsed = Elapsed(start)
+	}()
+
+	messages, err := promptMessages(mode, word, lang)
+	if err != nil {
+		return result, err
+	}
+
+	if err := c.ChatJSON(messages, mode.schema(), &answer); err != nil {
+		return result, err
+	}
+
+	result.List = answer.rows(word)
+	return result, nil
+}
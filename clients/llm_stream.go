@@ -0,0 +1,172 @@
+package clients
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sahib/model"
+	"strings"
+	"time"
+)
+
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta ChatMessage `json:"delta"`
+	} `json:"choices"`
+	// Sent with the last chunk, or with all of them by some providers
+	Usage *chatUsage `json:"usage"`
+}
+
+// chatStream sends the messages asking for a streamed answer (server sent
+// events), onDelta is called with the content received so far after every chunk.
+func (c *LLMClient) chatStream(ctx context.Context, messages []ChatMessage, extra map[string]interface{}, onDelta func(content string)) (string, error) {
+	if c.StreamUsage {
+		withUsage := map[string]interface{}{"stream_options": map[string]interface{}{"include_usage": true}}
+		for k, v := range extra {
+			withUsage[k] = v
+		}
+		extra = withUsage
+	}
+
+	rawResp, err := c.post(ctx, messages, c.Temperature, extra, true)
+	if err != nil {
+		return "", err
+	}
+	defer rawResp.Body.Close()
+
+	var content strings.Builder
+	var usage *chatUsage
+	defer func() {
+		// The usage only comes with the last chunk, an answer cancelled
+		// midway still consumed tokens.
+		if usage == nil {
+			usage = estimateUsage(messages, content.String())
+		}
+		c.recordUsage(usage)
+	}()
+	scanner := bufio.NewScanner(rawResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		chunk := chatStreamChunk{}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return content.String(), fmt.Errorf("Error deserializing %s stream chunk: %w", c.Name, err)
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(content.String())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return content.String(), fmt.Errorf("Error reading %s stream: %w", c.Name, err)
+	}
+
+	return content.String(), nil
+}
+
+// streamedItems returns the objects of the array under the given key that are
+// complete in a partial JSON answer.
+func streamedItems(content string, key string) []json.RawMessage {
+	idx := strings.Index(content, `"`+key+`"`)
+	if idx == -1 {
+		return nil
+	}
+	open := strings.IndexByte(content[idx:], '[')
+	if open == -1 {
+		return nil
+	}
+
+	items := []json.RawMessage{}
+	depth, start := 0, -1
+	inString, escaped := false, false
+	for i := idx + open + 1; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 && start != -1 {
+				items = append(items, json.RawMessage(content[start:i+1]))
+				start = -1
+			}
+		case ch == ']' && depth == 0:
+			return items
+		}
+	}
+
+	return items
+}
+
+// QueryStream is like QueryPrompt but the rows are sent to onRow as soon as
+// they are received. The request is aborted when the context is done, in which
+// case the rows received so far are returned.
+func (c *LLMClient) QueryStream(ctx context.Context, mode PromptMode, word string, lang model.Language, onRow func(model.Translation)) (*model.Translations, error) {
+	result := &model.Translations{}
+	answer := modeAnswer{mode: mode}
+
+	start := time.Now()
+	defer func() {
+		result.Elapsed = Elapsed(start)
+	}()
+
+	messages, err := promptMessages(mode, word, lang)
+	if err != nil {
+		return result, err
+	}
+	schema := mode.schema()
+
+	sent := 0
+	content, err := c.chatStream(ctx, messages, c.responseFormat(schema), func(content string) {
+		items := streamedItems(content, mode.ListKey)
+		for ; sent < len(items); sent++ {
+			item := map[string]string{}
+			if err := json.Unmarshal(items[sent], &item); err != nil || item[mode.ArabicKey] == "" {
+				continue
+			}
+			row := mode.row(item)
+			result.List = append(result.List, row)
+			onRow(row)
+		}
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, err
+	}
+	log.Printf("%s streamed response: %s", c.Name, content)
+
+	if err := c.decodeOrRepair(ctx, messages, content, schema, &answer); err != nil {
+		return result, err
+	}
+
+	result.List = answer.rows(word)
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sahib/model"
+	"testing"
+)
+
+// recordingUsage keeps the recorded usage and refuses the requests once the
+// budget is spent.
+type recordingUsage struct {
+	recorded []model.LLMUsage
+	checked  []string
+	spent    bool
+}
+
+func (u *recordingUsage) RecordUsage(usage model.LLMUsage) error {
+	u.recorded = append(u.recorded, usage)
+	return nil
+}
+
+func (u *recordingUsage) CheckBudget(user string) error {
+	u.checked = append(u.checked, user)
+	if u.spent {
+		return errors.New("budget spent")
+	}
+	return nil
+}
+
+// newTestLLMServer answers every chat request with the content and keeps the
+// last request body.
+func newTestLLMServer(t *testing.T, content string, request *map[string]interface{}, headers *http.Header) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			t.Errorf("invalid request body: %s", err)
+		}
+		*headers = r.Header.Clone()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+			"usage":   map[string]int{"prompt_tokens": 12, "completion_tokens": 5},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLLMChat(t *testing.T) {
+	var request map[string]interface{}
+	var headers http.Header
+	server := newTestLLMServer(t, "كَتَبَ", &request, &headers)
+
+	usage := &recordingUsage{}
+	client := LLMClient{
+		Name:      "Test",
+		BaseURL:   server.URL + "/v1/",
+		Model:     "small",
+		ApiKey:    "secret",
+		MaxTokens: 100,
+		Extra:     map[string]interface{}{"top_k": 3},
+	}
+	llm := client.WithUsage(usage, "amina")
+
+	content, err := llm.Chat([]ChatMessage{{Role: "user", Content: "كتب"}}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "كَتَبَ" {
+		t.Errorf("Chat() = %q", content)
+	}
+
+	want := map[string]interface{}{
+		"model":       "small",
+		"messages":    []interface{}{map[string]interface{}{"role": "user", "content": "كتب"}},
+		"temperature": 0.5,
+		"stream":      false,
+		"max_tokens":  float64(100),
+		"top_k":       float64(3),
+	}
+	if !reflect.DeepEqual(request, want) {
+		t.Errorf("request = %v, want %v", request, want)
+	}
+	if got := headers.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	wantUsage := []model.LLMUsage{{Provider: "Test", Model: "small", User: "amina", PromptTokens: 12, CompletionTokens: 5}}
+	if !reflect.DeepEqual(usage.recorded, wantUsage) {
+		t.Errorf("recorded usage = %+v, want %+v", usage.recorded, wantUsage)
+	}
+	if client.Usage != nil {
+		t.Error("WithUsage should return a copy")
+	}
+
+	// No request once the budget is spent
+	usage.spent = true
+	request = nil
+	if _, err := llm.Chat([]ChatMessage{{Role: "user", Content: "كتب"}}, 0.5); err == nil || request != nil {
+		t.Errorf("the request should be refused, got %v and %v", err, request)
+	}
+	if want := []string{"amina", "amina"}; !reflect.DeepEqual(usage.checked, want) {
+		t.Errorf("checked budgets = %q, want %q", usage.checked, want)
+	}
+}
+
+func TestLLMChatEmptyAnswer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	client := LLMClient{Name: "Test", BaseURL: server.URL}
+	if _, err := client.Chat([]ChatMessage{{Role: "user", Content: "كتب"}}, 0); err == nil {
+		t.Error("expected an error without choices")
+	}
+}
+
+func TestLLMFromEnv(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		client, err := LLMFromEnv("SAHIB_TEST")
+		if client != nil || err != nil {
+			t.Errorf("LLMFromEnv() = %+v, %v, want nil", client, err)
+		}
+	})
+
+	t.Run("preset with overrides", func(t *testing.T) {
+		t.Setenv("SAHIB_TEST_PRESET", "ollama")
+		t.Setenv("SAHIB_TEST_MODEL", "qwen2.5")
+		t.Setenv("SAHIB_TEST_TEMPERATURE", "0")
+		t.Setenv("SAHIB_TEST_JSON_SCHEMA", "false")
+
+		client, err := LLMFromEnv("SAHIB_TEST")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Preset != "ollama" || client.BaseURL != "http://localhost:11434/v1" || client.Model != "qwen2.5" ||
+			client.Temperature != 0 || client.MaxTokens != 1000 || client.JSONSchema {
+			t.Errorf("LLMFromEnv() = %+v", client)
+		}
+	})
+
+	t.Run("custom endpoint", func(t *testing.T) {
+		t.Setenv("SAHIB_TEST_URL", "http://localhost:1234/v1")
+		t.Setenv("SAHIB_TEST_MAX_TOKENS", "50")
+
+		client, err := LLMFromEnv("SAHIB_TEST")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Preset != "" || client.BaseURL != "http://localhost:1234/v1" || client.MaxTokens != 50 {
+			t.Errorf("LLMFromEnv() = %+v", client)
+		}
+	})
+
+	for name, env := range map[string][2]string{
+		"unknown preset":      {"SAHIB_TEST_PRESET", "mistral"},
+		"invalid temperature": {"SAHIB_TEST_TEMPERATURE", "warm"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SAHIB_TEST_URL", "http://localhost:1234/v1")
+			t.Setenv(env[0], env[1])
+			if _, err := LLMFromEnv("SAHIB_TEST"); err == nil {
+				t.Errorf("expected an error for %s=%s", env[0], env[1])
+			}
+		})
+	}
+}
+
+func TestPerplexity(t *testing.T) {
+	client := Perplexity("key")
+	if client.BaseURL != "https://api.perplexity.ai" || client.Model != "sonar-pro" || client.ApiKey != "key" {
+		t.Errorf("Perplexity() = %+v", client)
+	}
+	if _, ok := client.Extra["search_domain_filter"]; !ok {
+		t.Error("the perplexity fields should be sent")
+	}
+}
@@ -3,6 +3,7 @@ package clients
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"sahib/model"
 	"strings"
 	"time"
@@ -10,19 +11,39 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+const MaanyURL = "https://www.almaany.com"
+
+// Words in the headings of the maany sections, in the languages of the site.
+var (
+	maanyExampleHeadings = []string{"مثال", "أمثلة", "example", "exemple", "ejemplo", "beispiel", "örnek", "esempi"}
+	maanyRelatedHeadings = []string{"ذات صلة", "related", "connexe", "relacionad", "verwandt", "ilgili", "correlat"}
+)
+
 func QueryMaany(word string, lang model.Language) (*model.Translations, error) {
-	url := fmt.Sprintf("https://www.almaany.com/%s/dict/ar-%s/%s/?c=Tout", lang.Code, lang.Code, word)
+	return QueryMaanyCategory(word, lang, "")
+}
+
+// QueryMaanyCategory only returns the translations of a category (see
+// model.Translations.Categories), all of them if it is empty.
+func QueryMaanyCategory(word string, lang model.Language, category string) (*model.Translations, error) {
+	link := fmt.Sprintf("%s/%s/dict/ar-%s/%s/", MaanyURL, lang.Code, lang.Code, url.PathEscape(word))
+	if category != "" {
+		link += "?c=" + url.QueryEscape(category)
+	}
 	results := &model.Translations{
-		Link: url,
+		Link:     link,
+		Category: category,
+		Word:     word,
+		Lang:     lang.Code,
 	}
 	start := time.Now()
 	defer func() {
-		results.Elapsed = elapsed(start)
+		results.Elapsed = Elapsed(start)
 	}()
 
-	res, err := queryURL("GET", url, nil, nil, true)
+	res, err := queryURL("GET", link, nil, nil, true)
 	if err != nil {
-		return results, fmt.Errorf("failed to query maany at %s: %w",url, err)
+		return results, fmt.Errorf("failed to query maany at %s: %w", link, err)
 	}
 	defer res.Body.Close()
 
@@ -36,26 +57,94 @@ func QueryMaany(word string, lang model.Language) (*model.Translations, error) {
 
 	log.Printf("Done parsing")
 
-	toTashkil := []string{}
-	doc.Find(".panel-lightyellow").Find(".row").Each(func(i int, s *goquery.Selection) {
-		arabic := strings.Trim(s.Find(".text-left").Text(), " ..")
-		translation := strings.Trim(s.Find(".text-right").Text(), " .")
+	readMaany(doc, results)
 
-		toTashkil = append(toTashkil, strings.TrimSpace(arabic))
+	return results, nil
+}
 
-		results.List = append(results.List, model.Translation{Arabic: arabic, Translation: translation})
+// readMaany fills the results with the translations, the examples, the
+// related words and the categories of a maany page.
+func readMaany(doc *goquery.Document, results *model.Translations) {
+	// Adding the tashkil is very slow so it is done in a second pass (see Diacritizer)
+	doc.Find(".panel-lightyellow").Find(".row").Each(func(i int, s *goquery.Selection) {
+		results.List = append(results.List, maanyRow(s))
 	})
 
-	// TODO: tashkil is very slow so do it in two paths
-	// tashkil, err := tashkil(toTashkil)
-	// if err != nil {
-	// 	log.Printf("Couldn't add tashkil to result: %+v: %s", results, err)
-	// 	return results, nil
-	// }
+	doc.Find(".panel").Each(func(i int, panel *goquery.Selection) {
+		heading := strings.ToLower(panel.Find(".panel-heading").First().Text())
+		switch {
+		case containsAny(heading, maanyExampleHeadings):
+			panel.Find(".row").Each(func(j int, s *goquery.Selection) {
+				if row := maanyRow(s); row.Arabic != "" {
+					results.Examples = append(results.Examples, row)
+				}
+			})
+		case containsAny(heading, maanyRelatedHeadings):
+			panel.Find(".panel-body a").Each(func(j int, a *goquery.Selection) {
+				related := model.RelatedWord{Arabic: strings.TrimSpace(a.Text())}
+				if href, ok := a.Attr("href"); ok {
+					related.Link = absoluteMaanyURL(href)
+				}
+				if related.Arabic != "" {
+					results.Related = append(results.Related, related)
+				}
+			})
+		}
+	})
 
-	// for i := range results.List {
-	// 	results.List[i].Arabic = tashkil[i]
-	// }
+	results.Categories = maanyCategories(doc)
+}
 
-	return results, nil
+// maanyRow reads a row of translation, the category or part of speech is
+// kept in Meta.
+func maanyRow(s *goquery.Selection) model.Translation {
+	arabic := strings.Trim(s.Find(".text-left").Text(), " ..")
+	category := strings.TrimSpace(s.Find(".text-muted, .category").First().Text())
+	// The category follows the translation in the same cell
+	translation := strings.TrimSuffix(strings.TrimSpace(s.Find(".text-right").Text()), category)
+
+	return model.Translation{
+		Arabic:      strings.TrimSpace(arabic),
+		Translation: strings.Trim(strings.TrimSpace(translation), " ."),
+		Meta:        category,
+	}
+}
+
+// maanyCategories returns the categories the results can be filtered by, the
+// ones linked by the page with the c parameter.
+func maanyCategories(doc *goquery.Document) []model.Category {
+	categories := []model.Category{}
+	seen := map[string]bool{}
+	doc.Find("a[href*='c=']").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+
+		value := u.Query().Get("c")
+		name := strings.TrimSpace(a.Text())
+		if value == "" || name == "" || seen[value] {
+			return
+		}
+		seen[value] = true
+		categories = append(categories, model.Category{Name: name, Value: value})
+	})
+	return categories
+}
+
+func absoluteMaanyURL(href string) string {
+	if strings.HasPrefix(href, "/") {
+		return MaanyURL + href
+	}
+	return href
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sahib/model"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Numbering of the meanings of an entry: "1 -", "٢.", "3)"...
+var definitionSeparator = regexp.MustCompile(`(?:^|\s)[0-9٠-٩]+\s*[-–.)]\s`)
+
+// Examples are quoted in the definitions
+var quotedExample = regexp.MustCompile(`«([^»]+)»`)
+
+// QueryMaanyArabic returns the definitions of the arabic-arabic dictionaries
+// of maany (المعجم الوسيط, المعجم الغني...). The language is ignored.
+func QueryMaanyArabic(word string, ignored model.Language) (*model.Translations, error) {
+	link := fmt.Sprintf("%s/ar/dict/ar-ar/%s/", MaanyURL, url.PathEscape(word))
+	results := &model.Translations{Link: link}
+	start := time.Now()
+	defer func() {
+		results.Elapsed = Elapsed(start)
+	}()
+
+	res, err := queryURL("GET", link, nil, nil, true)
+	if err != nil {
+		return results, fmt.Errorf("failed to query maany at %s: %w", link, err)
+	}
+	defer res.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return results, fmt.Errorf("failed to parse html body: %w", err)
+	}
+
+	doc.Find("ul.meaning-results > li").Each(func(i int, s *goquery.Selection) {
+		entry := arabicDefinition(s)
+		if entry.Headword == "" || len(entry.Definitions) == 0 {
+			return
+		}
+
+		results.Monolingual = append(results.Monolingual, entry)
+		// Also as rows to be usable by the glossaries and the copy button
+		results.List = append(results.List, model.Translation{
+			Arabic:      entry.Headword,
+			Translation: strings.Join(entry.Definitions, " ؛ "),
+		})
+	})
+
+	return results, nil
+}
+
+func arabicDefinition(s *goquery.Selection) model.ArabicDefinition {
+	entry := model.ArabicDefinition{}
+
+	s = s.Clone()
+	headword := s.Find("h2, h3, .word, strong, b").First()
+	entry.Headword = strings.TrimSpace(strings.Trim(strings.TrimSpace(headword.Text()), ":"))
+	headword.Remove()
+
+	s.Find(".example, q").Each(func(i int, e *goquery.Selection) {
+		if example := strings.TrimSpace(e.Text()); example != "" {
+			entry.Examples = append(entry.Examples, example)
+		}
+		e.Remove()
+	})
+
+	text := s.Text()
+	for _, m := range quotedExample.FindAllStringSubmatch(text, -1) {
+		entry.Examples = append(entry.Examples, strings.TrimSpace(m[1]))
+	}
+	text = quotedExample.ReplaceAllString(text, "")
+
+	for _, line := range strings.Split(text, "\n") {
+		for _, def := range definitionSeparator.Split(line, -1) {
+			def = strings.Trim(strings.TrimSpace(def), ":،؛.-")
+			if len([]rune(def)) > 1 {
+				entry.Definitions = append(entry.Definitions, strings.TrimSpace(def))
+			}
+		}
+	}
+
+	return entry
+}
@@ -0,0 +1,62 @@
+package clients
+
+import (
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestArabicDefinition(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want model.ArabicDefinition
+	}{
+		{
+			name: "numbered meanings",
+			html: `<li><h2>كَتَبَ :</h2> 1 - كَتَبَ الكِتابَ كَتْباً، وكِتاباً، وكِتابةً: خَطَّهُ. 2 - كَتَبَ الشيءَ: جمعه. 3) ج</li>`,
+			want: model.ArabicDefinition{
+				Headword:    "كَتَبَ",
+				Definitions: []string{"كَتَبَ الكِتابَ كَتْباً، وكِتاباً، وكِتابةً: خَطَّهُ", "كَتَبَ الشيءَ: جمعه"},
+			},
+		},
+		{
+			name: "quoted examples",
+			html: `<li><h3>دَرَسَ</h3> ١- دَرَسَ الكتابَ: قرأه وأقبل عليه ليحفظه ويفهمه. «دَرَسَ الفقهَ» ٢- دَرَسَ الأثرُ: عفا وانمحى. «دَرَسَتِ الدارُ»</li>`,
+			want: model.ArabicDefinition{
+				Headword:    "دَرَسَ",
+				Definitions: []string{"دَرَسَ الكتابَ: قرأه وأقبل عليه ليحفظه ويفهمه", "دَرَسَ الأثرُ: عفا وانمحى"},
+				Examples:    []string{"دَرَسَ الفقهَ", "دَرَسَتِ الدارُ"},
+			},
+		},
+		{
+			name: "one meaning per line",
+			html: "<li><strong>قَلَم:</strong>\n<p>٢. أداةٌ يُكتب بها</p>\n<p>٣. القِدْح يُضرب به على الشيء <span class=\"example\">إِذْ يُلْقُونَ أَقْلامَهُمْ</span></p></li>",
+			want: model.ArabicDefinition{
+				Headword:    "قَلَم",
+				Definitions: []string{"أداةٌ يُكتب بها", "القِدْح يُضرب به على الشيء"},
+				Examples:    []string{"إِذْ يُلْقُونَ أَقْلامَهُمْ"},
+			},
+		},
+		{
+			name: "not numbered",
+			html: `<li><b>كِتابة</b> مصدر كَتَبَ</li>`,
+			want: model.ArabicDefinition{Headword: "كِتابة", Definitions: []string{"مصدر كَتَبَ"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<ul class="meaning-results">` + test.html + `</ul>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := arabicDefinition(doc.Find("ul.meaning-results > li")); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("arabicDefinition() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package clients
+
+import (
+	"os"
+	"reflect"
+	"sahib/model"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestReadMaany(t *testing.T) {
+	f, err := os.Open("testdata/maany_fr.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := &model.Translations{}
+	readMaany(doc, results)
+
+	list := []model.Translation{
+		// The category is removed from the translation
+		{Arabic: "كَتَبَ", Translation: "écrire", Meta: "فعل"},
+		{Arabic: "كُتُب", Translation: "livres", Meta: "اسم"},
+		{Arabic: "كَاتِب", Translation: "écrivain"},
+	}
+	if !reflect.DeepEqual(results.List, list) {
+		t.Errorf("List = %+v, want %+v", results.List, list)
+	}
+
+	// The rows without arabic are skipped
+	examples := []model.Translation{{Arabic: "كَتَبَ الوَلَدُ رِسَالَةً", Translation: "le garçon a écrit une lettre"}}
+	if !reflect.DeepEqual(results.Examples, examples) {
+		t.Errorf("Examples = %+v, want %+v", results.Examples, examples)
+	}
+
+	related := []model.RelatedWord{
+		{Arabic: "كِتَاب", Link: MaanyURL + "/fr/dict/ar-fr/%D9%83%D8%AA%D8%A7%D8%A8/"},
+		{Arabic: "مَكْتَب", Link: "https://www.almaany.com/fr/dict/ar-fr/%D9%85%D9%83%D8%AA%D8%A8/"},
+	}
+	if !reflect.DeepEqual(results.Related, related) {
+		t.Errorf("Related = %+v, want %+v", results.Related, related)
+	}
+
+	categories := []model.Category{{Name: "Verbe", Value: "الفعل"}, {Name: "Nom", Value: "الاسم"}}
+	if !reflect.DeepEqual(results.Categories, categories) {
+		t.Errorf("Categories = %+v, want %+v", results.Categories, categories)
+	}
+}
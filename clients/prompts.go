@@ -0,0 +1,343 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"sahib/model"
+	"strings"
+	"text/template"
+)
+
+// PromptMode is a kind of question asked to the LLMs about a word. The answer
+// is a JSON object with an optional header string (e.g. the translation of
+// the word) and a list of items, each item becoming a row of the results.
+type PromptMode struct {
+	Key  string
+	Name string
+	// text/template source of the prompt, see PromptData for the fields
+	Template          string
+	HeaderKey         string
+	HeaderDescription string
+	ListKey           string
+	// Fields of the list items shown in the arabic, translation and meta
+	// columns. Only Arabic is mandatory.
+	ArabicKey      string
+	TranslationKey string
+	MetaKey        string
+	// Description of each item field, used to show the expected format to the model
+	Fields map[string]string
+	// Number of items asked to the model, DefaultPromptCount if 0
+	Count int
+}
+
+// PromptData is given to the prompt templates.
+type PromptData struct {
+	Word     string
+	Language string
+	Count    int
+	// Example of the expected JSON output
+	Format string
+}
+
+// DefaultPromptCount is the number of items asked by default.
+const DefaultPromptCount = 5
+
+const (
+	PromptExamples     = "examples"
+	PromptUsage        = "usage"
+	PromptSynonyms     = "synonyms"
+	PromptCollocations = "collocations"
+	PromptEtymology    = "etymology"
+	PromptMinimalPairs = "minimal_pairs"
+)
+
+func PromptModes() []PromptMode {
+	return []PromptMode{
+		{
+			Key:  PromptExamples,
+			Name: "Examples",
+			Template: `Give me {{.Count}} examples of useful and relevant sentences from medias or stories with the proper arabic diacritics (harakat) on all words.
+
+The word is: {{.Word}}
+
+The translation language should be {{.Language}}
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			HeaderKey:         "translation",
+			HeaderDescription: "The translation of the word",
+			ListKey:           "examples",
+			ArabicKey:         "sentence",
+			TranslationKey:    "translation",
+			Fields: map[string]string{
+				"sentence":    "An example of sentence",
+				"translation": "The translation of the sentence in the target language",
+			},
+		},
+		{
+			Key:  PromptUsage,
+			Name: "Usage notes",
+			Template: `Explain how the arabic word {{.Word}} is used depending on the register (Modern Standard Arabic, classical, formal, colloquial) and the main dialects, with up to {{.Count}} notes each illustrated by a short example with the proper arabic diacritics (harakat).
+
+The explanations should be in {{.Language}}.
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			ListKey:        "notes",
+			ArabicKey:      "example",
+			TranslationKey: "explanation",
+			MetaKey:        "register",
+			Fields: map[string]string{
+				"register":    "The register or dialect (e.g. MSA, Egyptian, Levantine)",
+				"example":     "An example of usage in arabic",
+				"explanation": "How the word is used in this register and the translation of the example",
+			},
+		},
+		{
+			Key:  PromptSynonyms,
+			Name: "Synonyms & antonyms",
+			Template: `Give me up to {{.Count}} synonyms and up to {{.Count}} antonyms of the arabic word {{.Word}} with the proper arabic diacritics (harakat) and their nuances.
+
+The translations should be in {{.Language}}.
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			ListKey:        "words",
+			ArabicKey:      "word",
+			TranslationKey: "translation",
+			MetaKey:        "relation",
+			Fields: map[string]string{
+				"word":        "The synonym or antonym",
+				"relation":    "synonym or antonym",
+				"translation": "The translation and how it differs from the searched word",
+			},
+		},
+		{
+			Key:  PromptCollocations,
+			Name: "Collocations",
+			Template: `Give me the {{.Count}} most common collocations (words frequently used together) with the arabic word {{.Word}}, with the proper arabic diacritics (harakat).
+
+The translations should be in {{.Language}}.
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			ListKey:        "collocations",
+			ArabicKey:      "collocation",
+			TranslationKey: "translation",
+			Fields: map[string]string{
+				"collocation": "The collocation in arabic",
+				"translation": "Its translation",
+			},
+		},
+		{
+			Key:  PromptEtymology,
+			Name: "Etymology",
+			Template: `Explain the etymology of the arabic word {{.Word}}: its root, its pattern (wazn), how its meaning derives from the root and if it was borrowed from or into other languages. Use at most {{.Count}} steps and the proper arabic diacritics (harakat).
+
+The explanations should be in {{.Language}}.
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			ListKey:        "steps",
+			ArabicKey:      "form",
+			TranslationKey: "explanation",
+			Fields: map[string]string{
+				"form":        "The arabic form (root, pattern, related or borrowed word)",
+				"explanation": "The explanation of this step",
+			},
+		},
+		{
+			Key:  PromptMinimalPairs,
+			Name: "Minimal pairs",
+			Template: `Give me up to {{.Count}} arabic words that are easily confused with {{.Word}} because they look or sound similar (minimal pairs), with the proper arabic diacritics (harakat).
+
+The explanations should be in {{.Language}}.
+
+The output should be in JSON format like so:
+
+{{.Format}}
+`,
+			ListKey:        "pairs",
+			ArabicKey:      "word",
+			TranslationKey: "translation",
+			MetaKey:        "difference",
+			Fields: map[string]string{
+				"word":        "The similar word",
+				"translation": "Its translation",
+				"difference":  "What differs from the searched word (letter, vowel, pronunciation)",
+			},
+		},
+	}
+}
+
+// FindPromptMode returns the prompt mode with the given key.
+func FindPromptMode(key string) (PromptMode, bool) {
+	for _, mode := range PromptModes() {
+		if mode.Key == key {
+			return mode, true
+		}
+	}
+
+	return PromptMode{}, false
+}
+
+// WithTemplate returns the prompt mode of a user template, its answer format
+// stays the one of the mode.
+func WithTemplate(t model.PromptTemplate) (PromptMode, error) {
+	mode, ok := FindPromptMode(t.Mode)
+	if !ok {
+		return mode, fmt.Errorf("unknown prompt mode %s for template %s", t.Mode, t.Name)
+	}
+
+	mode.Name = t.Name
+	mode.Template = t.Template
+	mode.Count = t.Count
+
+	// Catch the template errors early rather than at search time
+	_, err := mode.Prompt("كتب", model.Languages()[0])
+	return mode, err
+}
+
+func (m PromptMode) itemKeys() []string {
+	keys := []string{m.ArabicKey}
+	for _, k := range []string{m.TranslationKey, m.MetaKey} {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (m PromptMode) schema() Schema {
+	itemProperties := map[string]interface{}{}
+	for _, k := range m.itemKeys() {
+		itemProperties[k] = map[string]interface{}{"type": "string", "minLength": 1}
+	}
+
+	properties := map[string]interface{}{
+		m.ListKey: map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"required":             m.itemKeys(),
+				"additionalProperties": false,
+				"properties":           itemProperties,
+			},
+		},
+	}
+	required := []string{m.ListKey}
+	if m.HeaderKey != "" {
+		properties[m.HeaderKey] = map[string]interface{}{"type": "string", "minLength": 1}
+		required = append([]string{m.HeaderKey}, required...)
+	}
+
+	return Schema{
+		Name: m.Key,
+		Definition: map[string]interface{}{
+			"type":                 "object",
+			"required":             required,
+			"additionalProperties": false,
+			"properties":           properties,
+		},
+	}
+}
+
+// format returns an example of the JSON answer expected from the model.
+func (m PromptMode) format() string {
+	item := make([]string, 0, 3)
+	for _, k := range m.itemKeys() {
+		item = append(item, fmt.Sprintf("%q: %q", k, m.Fields[k]))
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	if m.HeaderKey != "" {
+		fmt.Fprintf(&b, "    %q: %q,\n", m.HeaderKey, m.HeaderDescription)
+	}
+	fmt.Fprintf(&b, "    %q: [\n         {%s},\n     ]\n}", m.ListKey, strings.Join(item, ", "))
+	return b.String()
+}
+
+// Prompt renders the prompt of the mode for a word.
+func (m PromptMode) Prompt(word string, lang model.Language) (string, error) {
+	count := m.Count
+	if count <= 0 {
+		count = DefaultPromptCount
+	}
+
+	tmpl, err := template.New(m.Key).Parse(m.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s prompt template: %w", m.Key, err)
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, PromptData{Word: word, Language: lang.Name, Count: count, Format: m.format()})
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", m.Key, err)
+	}
+
+	return b.String(), nil
+}
+
+// modeAnswer is the decoded answer of a model to a prompt mode.
+type modeAnswer struct {
+	mode   PromptMode
+	header string
+	items  []map[string]string
+}
+
+func (a *modeAnswer) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if a.mode.HeaderKey != "" {
+		if err := json.Unmarshal(fields[a.mode.HeaderKey], &a.header); err != nil {
+			return fmt.Errorf("invalid %s: %w", a.mode.HeaderKey, err)
+		}
+	}
+
+	return json.Unmarshal(fields[a.mode.ListKey], &a.items)
+}
+
+func (a *modeAnswer) validate() error {
+	for i, item := range a.items {
+		if !strings.ContainsFunc(item[a.mode.ArabicKey], isArabic) {
+			return fmt.Errorf("$.%s[%d].%s should be in arabic", a.mode.ListKey, i, a.mode.ArabicKey)
+		}
+	}
+	return nil
+}
+
+func (m PromptMode) row(item map[string]string) model.Translation {
+	return model.Translation{
+		Arabic:      item[m.ArabicKey],
+		Translation: item[m.TranslationKey],
+		Meta:        item[m.MetaKey],
+	}
+}
+
+func (a *modeAnswer) rows(word string) []model.Translation {
+	rows := []model.Translation{}
+	if a.header != "" {
+		rows = append(rows, model.Translation{Arabic: word, Translation: a.header})
+	}
+
+	for _, item := range a.items {
+		rows = append(rows, a.mode.row(item))
+	}
+
+	return rows
+}
@@ -0,0 +1,118 @@
+package clients
+
+import (
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+func TestPromptModes(t *testing.T) {
+	lang := model.Language{Code: "fr", Name: "French"}
+	for _, mode := range PromptModes() {
+		t.Run(mode.Key, func(t *testing.T) {
+			prompt, err := mode.Prompt("كتب", lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"كتب", "French", `"` + mode.ListKey + `": [`, `"` + mode.ArabicKey + `": `} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("the prompt doesn't contain %q:\n%s", want, prompt)
+				}
+			}
+			if strings.Contains(prompt, "{{") || strings.Contains(prompt, "<no value>") {
+				t.Errorf("the template isn't fully rendered:\n%s", prompt)
+			}
+
+			for _, k := range mode.itemKeys() {
+				if mode.Fields[k] == "" {
+					t.Errorf("field %s has no description", k)
+				}
+			}
+		})
+	}
+}
+
+func TestPromptModeSchema(t *testing.T) {
+	examples, _ := FindPromptMode(PromptExamples)
+	synonyms, _ := FindPromptMode(PromptSynonyms)
+
+	tests := []struct {
+		name   string
+		mode   PromptMode
+		answer string
+		// Substring of the expected error, empty if the answer is valid
+		err  string
+		want []model.Translation
+	}{
+		{
+			name:   "header and items",
+			mode:   examples,
+			answer: `{"translation": "écrire", "examples": [{"sentence": "كَتَبَ الوَلَدُ", "translation": "le garçon a écrit"}]}`,
+			want:   []model.Translation{{Arabic: "كتب", Translation: "écrire"}, {Arabic: "كَتَبَ الوَلَدُ", Translation: "le garçon a écrit"}},
+		},
+		{
+			name:   "meta column",
+			mode:   synonyms,
+			answer: `{"words": [{"word": "دَوَّنَ", "relation": "synonym", "translation": "noter"}]}`,
+			want:   []model.Translation{{Arabic: "دَوَّنَ", Translation: "noter", Meta: "synonym"}},
+		},
+		{name: "missing header", mode: examples, answer: `{"examples": [{"sentence": "كتب", "translation": "écrire"}]}`, err: "$.translation is missing"},
+		{name: "missing item field", mode: synonyms, answer: `{"words": [{"word": "دون", "translation": "noter"}]}`, err: "$.words[0].relation is missing"},
+		{name: "no header expected", mode: synonyms, answer: `{"translation": "écrire", "words": [{"word": "دون", "relation": "synonym", "translation": "noter"}]}`, err: "$.translation is not an allowed property"},
+		{name: "not arabic", mode: examples, answer: `{"translation": "écrire", "examples": [{"sentence": "kataba", "translation": "écrire"}]}`, err: "$.examples[0].sentence should be in arabic"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			answer := modeAnswer{mode: test.mode}
+			err := decodeJSON(test.answer, test.mode.schema(), &answer)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatal(err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("decodeJSON() = %v, want %q", err, test.err)
+			case test.err != "":
+				return
+			}
+
+			if got := answer.rows("كتب"); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("rows() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithTemplate(t *testing.T) {
+	mode, err := WithTemplate(model.PromptTemplate{
+		Name:     "Short",
+		Mode:     PromptExamples,
+		Template: "{{.Count}} phrases avec {{.Word}} en {{.Language}}\n{{.Format}}",
+		Count:    3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode.Name != "Short" || mode.ListKey != "examples" || mode.HeaderKey != "translation" {
+		t.Errorf("WithTemplate() = %+v, the answer format should be the one of the mode", mode)
+	}
+
+	prompt, err := mode.Prompt("قلم", model.Language{Code: "fr", Name: "French"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(prompt, "3 phrases avec قلم en French\n{") {
+		t.Errorf("Prompt() = %q", prompt)
+	}
+
+	for name, tmpl := range map[string]model.PromptTemplate{
+		"unknown mode":   {Name: "Bad", Mode: "poems", Template: "{{.Word}}"},
+		"syntax error":   {Name: "Bad", Mode: PromptExamples, Template: "{{.Word"},
+		"unknown field":  {Name: "Bad", Mode: PromptExamples, Template: "{{.Root}}"},
+		"function error": {Name: "Bad", Mode: PromptExamples, Template: `{{index .Word 10}}`},
+	} {
+		if _, err := WithTemplate(tmpl); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
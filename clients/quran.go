@@ -0,0 +1,107 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+	"sahib/model"
+	"strings"
+	"unicode"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Quran queries a corpus of the Quran imported with ImportQuran.
+type Quran struct {
+	db *sql.DB
+}
+
+func NewQuranClient(path string) (*Quran, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to init quran client: %w", err)
+	}
+
+	return &Quran{db: db}, nil
+}
+
+func (q *Quran) Close() error {
+	return q.db.Close()
+}
+
+// Concordance returns the verses where the root occurs, or the word (as
+// written, its stem or its lemma), in the order of the Quran. The word is
+// looked up without its clitics if it isn't found as is.
+func (q *Quran) Concordance(word string, byRoot bool) (*model.Concordance, error) {
+	c := &model.Concordance{Query: word, ByRoot: byRoot}
+
+	if byRoot {
+		return c, q.occurrences(c, `w.root = ?1`, quranKey(word))
+	}
+
+	for _, candidate := range append([]string{word}, Candidates(word)...) {
+		key := quranKey(candidate)
+		err := q.occurrences(c, `w.id IN (SELECT word_id FROM word_keys WHERE key = ?1)`, key)
+		if err != nil || c.Occurrences > 0 {
+			return c, err
+		}
+	}
+
+	return c, nil
+}
+
+func (q *Quran) occurrences(c *model.Concordance, condition string, key string) error {
+	rows, err := q.db.Query(`SELECT w.sura, w.aya, w.position, w.root, v.text FROM words w
+        JOIN verses v ON v.sura = w.sura AND v.aya = w.aya
+        WHERE `+condition+` ORDER BY w.sura, w.aya, w.position`, key)
+	if err != nil {
+		return fmt.Errorf("failed to query the quran: %w", err)
+	}
+	defer rows.Close()
+
+	// Indexes of the words of the current verse
+	var words []int
+	for rows.Next() {
+		var sura, aya, position int
+		var root, text string
+		if err := rows.Scan(&sura, &aya, &position, &root, &text); err != nil {
+			return fmt.Errorf("error while scanning verse: %w", err)
+		}
+		c.Occurrences++
+		if c.Root == "" {
+			c.Root = root
+		}
+
+		last := len(c.Verses) - 1
+		if last < 0 || c.Verses[last].Sura != sura || c.Verses[last].Aya != aya {
+			verse := model.QuranVerse{Sura: sura, Aya: aya}
+			verse.Words, words = verseWords(text)
+			c.Verses = append(c.Verses, verse)
+			last++
+		}
+
+		// The positions of the corpus start at 1
+		if position > 0 && position <= len(words) {
+			c.Verses[last].Words[words[position-1]].Match = true
+		}
+	}
+
+	return rows.Err()
+}
+
+// verseWords splits the text of a verse and returns the indexes of its
+// words, the pause marks between them aren't counted by the corpus.
+func verseWords(text string) ([]model.QuranWord, []int) {
+	tokens := []model.QuranWord{}
+	words := []int{}
+	for _, t := range strings.Fields(text) {
+		if strings.ContainsFunc(t, isArabicLetter) {
+			words = append(words, len(tokens))
+		}
+		tokens = append(tokens, model.QuranWord{Text: t})
+	}
+	return tokens, words
+}
+
+func isArabicLetter(r rune) bool {
+	return unicode.IsLetter(r) && unicode.Is(unicode.Arabic, r)
+}
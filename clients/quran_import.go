@@ -0,0 +1,252 @@
+package clients
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sahib/translit"
+	"strconv"
+	"strings"
+)
+
+// ImportQuran (re)creates the quran tables of the database from the text of
+// the Quran, in the sura|aya|text format of tanzil.net, and from the
+// morphology of the Quranic Arabic Corpus (one segment of word per line, in
+// extended Buckwalter). The word positions of the corpus match the words of
+// the uthmani text. It returns the number of imported verses and words.
+func ImportQuran(db *sql.DB, text io.Reader, morphology io.Reader) (int, int, error) {
+	schema := []string{
+		`DROP TABLE IF EXISTS verses`,
+		`DROP TABLE IF EXISTS words`,
+		`DROP TABLE IF EXISTS word_keys`,
+		`CREATE TABLE verses (
+            sura INTEGER NOT NULL,
+            aya INTEGER NOT NULL,
+            text TEXT NOT NULL,
+            PRIMARY KEY (sura, aya)
+        )`,
+		`CREATE TABLE words (
+            id INTEGER PRIMARY KEY,
+            sura INTEGER NOT NULL,
+            aya INTEGER NOT NULL,
+            position INTEGER NOT NULL,
+            form TEXT NOT NULL,
+            root TEXT NOT NULL
+        )`,
+		// The form, stem and lemma of the words in their two spellings
+		`CREATE TABLE word_keys (
+            word_id INTEGER NOT NULL REFERENCES words(id),
+            key TEXT NOT NULL
+        )`,
+	}
+
+	// The previous tables are kept if the import fails
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range schema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, 0, fmt.Errorf("failed to create the quran tables: %w", err)
+		}
+	}
+
+	verses, err := importVerses(tx, text)
+	if err != nil {
+		return verses, 0, err
+	}
+
+	words, err := importMorphology(tx, morphology)
+	if err != nil {
+		return verses, words, err
+	}
+
+	indexes := []string{
+		`CREATE INDEX word_keys_key ON word_keys(key)`,
+		`CREATE INDEX words_root ON words(root)`,
+	}
+	for _, stmt := range indexes {
+		if _, err := tx.Exec(stmt); err != nil {
+			return verses, words, fmt.Errorf("failed to index the quran: %w", err)
+		}
+	}
+
+	return verses, words, tx.Commit()
+}
+
+func importVerses(tx *sql.Tx, r io.Reader) (int, error) {
+	insert, err := tx.Prepare(`INSERT INTO verses (sura, aya, text) VALUES (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		// The file ends with its license in comments
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(l, "|", 3)
+		if len(parts) != 3 {
+			return count, fmt.Errorf("invalid verse at line %d, expected sura|aya|text", line)
+		}
+		sura, err1 := strconv.Atoi(parts[0])
+		aya, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return count, fmt.Errorf("invalid verse number at line %d", line)
+		}
+
+		if _, err := insert.Exec(sura, aya, parts[2]); err != nil {
+			return count, fmt.Errorf("failed to insert verse %d:%d: %w", sura, aya, err)
+		}
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// uthmani spells out the dagger alef and the small waw and ya of the uthmani
+// script like in the simple one, where the dagger alef is written as an alef
+// in some words (كتٰب, كتاب) and dropped in others (ذٰلك, ذلك).
+var (
+	uthmani       = strings.NewReplacer("ىٰ", "ى", "\u0670", "ا", "\u06E5", "", "\u06E6", "")
+	uthmaniNoAlef = strings.NewReplacer("ىٰ", "ى", "\u0670", "", "\u06E5", "", "\u06E6", "")
+)
+
+// quranKey is how the words of the Quran are looked up.
+func quranKey(word string) string {
+	return normalizeKey(uthmani.Replace(word))
+}
+
+// quranKeys returns the keys of the words, with both spellings of the dagger
+// alef.
+func quranKeys(words ...string) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, word := range words {
+		for _, key := range []string{quranKey(word), normalizeKey(uthmaniNoAlef.Replace(word))} {
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// corpusWord is a word of the corpus, made of its segments (prefixes, stem
+// and suffixes).
+type corpusWord struct {
+	sura, aya, position int
+	form, stem          string
+	lemma, root         string
+}
+
+// parseLocation parses the (sura:aya:word:segment) location of a segment.
+func parseLocation(location string) ([4]int, error) {
+	out := [4]int{}
+	parts := strings.Split(strings.Trim(location, "()"), ":")
+	if len(parts) != 4 {
+		return out, fmt.Errorf("invalid location %s", location)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, fmt.Errorf("invalid location %s", location)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+func importMorphology(tx *sql.Tx, r io.Reader) (int, error) {
+	insert, err := tx.Prepare(`INSERT INTO words (sura, aya, position, form, root) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	insertKey, err := tx.Prepare(`INSERT INTO word_keys (word_id, key) VALUES (?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insertKey.Close()
+
+	count := 0
+	var word *corpusWord
+	flush := func() error {
+		if word == nil {
+			return nil
+		}
+		res, err := insert.Exec(word.sura, word.aya, word.position, word.form, quranKey(word.root))
+		if err != nil {
+			return fmt.Errorf("failed to insert word %d:%d:%d: %w", word.sura, word.aya, word.position, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, key := range quranKeys(word.form, word.stem, word.lemma) {
+			if _, err := insertKey.Exec(id, key); err != nil {
+				return fmt.Errorf("failed to index word %d:%d:%d: %w", word.sura, word.aya, word.position, err)
+			}
+		}
+		count++
+		if count%10000 == 0 {
+			log.Printf("Imported %d words of the quran", count)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		l := scanner.Text()
+		if !strings.HasPrefix(l, "(") {
+			// Comments and header
+			continue
+		}
+
+		fields := strings.Split(l, "\t")
+		if len(fields) < 4 {
+			return count, fmt.Errorf("invalid segment at line %d", line)
+		}
+		loc, err := parseLocation(fields[0])
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if word == nil || word.sura != loc[0] || word.aya != loc[1] || word.position != loc[2] {
+			if err := flush(); err != nil {
+				return count, err
+			}
+			word = &corpusWord{sura: loc[0], aya: loc[1], position: loc[2]}
+		}
+
+		form := translit.FromBuckwalter(fields[1])
+		word.form += form
+		features := strings.Split(fields[3], "|")
+		if features[0] == "STEM" {
+			word.stem = form
+		}
+		for _, f := range features {
+			if lemma, ok := strings.CutPrefix(f, "LEM:"); ok {
+				word.lemma = translit.FromBuckwalter(lemma)
+			} else if root, ok := strings.CutPrefix(f, "ROOT:"); ok {
+				word.root = translit.FromBuckwalter(root)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read the morphology: %w", err)
+	}
+
+	return count, flush()
+}
@@ -0,0 +1,156 @@
+package clients
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTanzil = `1|1|بِسْمِ ٱللَّهِ ٱلرَّحْمَٰنِ ٱلرَّحِيمِ
+2|2|ذَٰلِكَ ٱلْكِتَٰبُ لَا رَيْبَ ۛ فِيهِ ۛ هُدًى لِّلْمُتَّقِينَ
+
+# Licensed under the tanzil.net terms of use
+`
+
+const testCorpus = `# Quranic Arabic Corpus morphology
+LOCATION	FORM	TAG	FEATURES
+(1:1:1:1)	bi	P	PREFIX|bi+
+(1:1:1:2)	somi	N	STEM|POS:N|LEM:{som|ROOT:smw|M|GEN
+(1:1:2:1)	{ll~ahi	PN	STEM|POS:PN|LEM:{ll~ah|ROOT:Alh|GEN
+(1:1:3:1)	{l	DET	PREFIX|Al+
+(1:1:3:2)	r~aHoma` + "`" + `ni	ADJ	STEM|POS:ADJ|LEM:r~aHoma` + "`" + `n|ROOT:rHm|MS|GEN
+(2:2:1:1)	*a` + "`" + `lika	DEM	STEM|POS:DEM|LEM:*a` + "`" + `lik|MS
+(2:2:2:1)	{lo	DET	PREFIX|Al+
+(2:2:2:2)	kita` + "`" + `bu	N	STEM|POS:N|LEM:kita` + "`" + `b|ROOT:ktb|M|NOM
+(2:2:3:1)	laA	NEG	STEM|POS:NEG|LEM:laA
+(2:2:4:1)	rayoba	N	STEM|POS:N|LEM:rayob|ROOT:ryb|M|ACC
+(2:2:5:1)	fiy	P	STEM|POS:P|LEM:fiy
+(2:2:5:2)	hi	PRON	SUFFIX|PRON:3MS
+`
+
+func newTestQuran(t *testing.T) *Quran {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "quran.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	verses, words, err := ImportQuran(db, strings.NewReader(testTanzil), strings.NewReader(testCorpus))
+	if err != nil || verses != 2 || words != 8 {
+		t.Fatalf("ImportQuran() = %d, %d, %v, want 2 verses and 8 words", verses, words, err)
+	}
+
+	q, err := NewQuranClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestQuranConcordance(t *testing.T) {
+	q := newTestQuran(t)
+
+	tests := []struct {
+		name   string
+		word   string
+		byRoot bool
+		// sura:aya of the verses and position of the matched word in its text
+		verses [][3]int
+		root   string
+	}{
+		{name: "dagger alef dropped", word: "الرحمن", verses: [][3]int{{1, 1, 2}}, root: "رحم"},
+		{name: "dagger alef written", word: "الرحمان", verses: [][3]int{{1, 1, 2}}, root: "رحم"},
+		{name: "stem", word: "رحمن", verses: [][3]int{{1, 1, 2}}, root: "رحم"},
+		{name: "demonstrative", word: "ذلك", verses: [][3]int{{2, 2, 0}}},
+		{name: "lemma", word: "كتاب", verses: [][3]int{{2, 2, 1}}, root: "كتب"},
+		{name: "root", word: "ك ت ب", byRoot: true, verses: [][3]int{{2, 2, 1}}, root: "كتب"},
+		// The pause mark before it isn't a word
+		{name: "clitic", word: "وفيه", verses: [][3]int{{2, 2, 5}}},
+		{name: "unknown", word: "قلم"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := q.Concordance(test.word, test.byRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var verses [][3]int
+			for _, v := range c.Verses {
+				for i, w := range v.Words {
+					if w.Match {
+						verses = append(verses, [3]int{v.Sura, v.Aya, i})
+					}
+				}
+			}
+			if !reflect.DeepEqual(verses, test.verses) || c.Occurrences != len(test.verses) || c.Root != test.root {
+				t.Errorf("Concordance(%q) = %v (%d occurrences, root %q), want %v (root %q)",
+					test.word, verses, c.Occurrences, c.Root, test.verses, test.root)
+			}
+		})
+	}
+}
+
+func TestQuranKeys(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"ٱلْكِتَٰبُ"}, []string{"الكتاب", "الكتب"}},
+		{[]string{"ذَٰلِكَ", "ذَٰلِك"}, []string{"ذالك", "ذلك"}},
+		{[]string{"عَلَىٰ"}, []string{"على"}},
+		// Small waw
+		{[]string{"بِهِۥ"}, []string{"به"}},
+		{[]string{""}, []string{}},
+	}
+
+	for _, test := range tests {
+		if got := quranKeys(test.words...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("quranKeys(%q) = %q, want %q", test.words, got, test.want)
+		}
+	}
+}
+
+func TestImportQuranInvalidMorphology(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quran.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, _, err := ImportQuran(db, strings.NewReader(testTanzil), strings.NewReader(testCorpus)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The verses of the text are read before the morphology fails
+	text := "1|1|قُلْ هُوَ ٱللَّهُ أَحَدٌ\n"
+	morphology := "(1:1:1:1)\tqul\tV\tSTEM|POS:V|LEM:qaAla|ROOT:qwl\n(1:1:x:1)\thuwa\tPRON\tSTEM|POS:PRON|3MS\n"
+	_, _, err = ImportQuran(db, strings.NewReader(text), strings.NewReader(morphology))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("ImportQuran() = %v, want an error at line 2", err)
+	}
+
+	q, err := NewQuranClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	c, err := q.Concordance("كتاب", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Verses) != 1 || c.Verses[0].Sura != 2 || c.Verses[0].Aya != 2 {
+		t.Errorf("Concordance() = %+v, want the verse of the previous text", c.Verses)
+	}
+	if c, _ := q.Concordance("قل", false); c == nil || len(c.Verses) != 0 {
+		t.Errorf("Concordance() found the verse of the failed import")
+	}
+}
@@ -0,0 +1,136 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema is the JSON schema an LLM answer must match, only the subset of
+// JSON schema needed by the prompts is validated: type, properties, required,
+// additionalProperties (when false), items, minItems and minLength.
+type Schema struct {
+	Name       string
+	Definition map[string]interface{}
+}
+
+// extractJSON returns the first JSON object found in the input, models tend
+// to surround it with text or markdown code fences.
+func extractJSON(input string) (json.RawMessage, error) {
+	var lastErr error = fmt.Errorf("no JSON object found")
+	for offset := 0; offset < len(input); {
+		start := strings.IndexByte(input[offset:], '{')
+		if start == -1 {
+			break
+		}
+		start += offset
+
+		var raw json.RawMessage
+		err := json.NewDecoder(strings.NewReader(input[start:])).Decode(&raw)
+		if err == nil {
+			return raw, nil
+		}
+		lastErr = err
+		offset = start + 1
+	}
+
+	return nil, lastErr
+}
+
+// decodeJSON extracts the JSON object of an answer, validates it against the
+// schema and decodes it in out.
+func decodeJSON(content string, schema Schema, out interface{}) error {
+	raw, err := extractJSON(content)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if err := validateSchema(value, schema.Definition, "$"); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return err
+	}
+
+	// Checks that can't be expressed with the schema
+	if v, ok := out.(interface{ validate() error }); ok {
+		return v.validate()
+	}
+
+	return nil
+}
+
+func validateSchema(value interface{}, schema map[string]interface{}, path string) error {
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an object", path)
+		}
+
+		required, _ := schema["required"].([]string)
+		for _, key := range required {
+			if _, ok := obj[key]; !ok {
+				return fmt.Errorf("%s.%s is missing", path, key)
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+			keys := make([]string, 0, len(obj))
+			for key := range obj {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if _, ok := properties[key]; !ok {
+					return fmt.Errorf("%s.%s is not an allowed property", path, key)
+				}
+			}
+		}
+
+		for key, sub := range properties {
+			if v, ok := obj[key]; ok {
+				if err := validateSchema(v, sub.(map[string]interface{}), path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an array", path)
+		}
+
+		if min, ok := schema["minItems"].(int); ok && len(arr) < min {
+			return fmt.Errorf("%s should have at least %d items, got %d", path, min, len(arr))
+		}
+
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, v := range arr {
+				if err := validateSchema(v, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s should be a string", path)
+		}
+
+		length := len([]rune(strings.TrimSpace(str)))
+		if min, ok := schema["minLength"].(int); ok && length < min {
+			return fmt.Errorf("%s should have at least %d characters, got %d", path, min, length)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: `{"a": 1}`, want: `{"a": 1}`},
+		{input: "Here is the answer:\n```json\n{\"a\": [1, 2]}\n```\nEnjoy!", want: `{"a": [1, 2]}`},
+		// The first brace isn't the start of a valid object
+		{input: `Use {curly} braces: {"a": "}"}`, want: `{"a": "}"}`},
+		{input: `{"a": {"b": true}} {"c": 1}`, want: `{"a": {"b": true}}`},
+		{input: "no json", err: true},
+		{input: `{"a": `, err: true},
+	}
+
+	for _, test := range tests {
+		raw, err := extractJSON(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("extractJSON(%q) = %s, want an error", test.input, raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("extractJSON(%q): %s", test.input, err)
+			continue
+		}
+		if string(raw) != test.want {
+			t.Errorf("extractJSON(%q) = %s, want %s", test.input, raw, test.want)
+		}
+	}
+}
+
+var testSchema = Schema{
+	Name: "examples",
+	Definition: map[string]interface{}{
+		"type":                 "object",
+		"required":             []string{"title", "examples"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{"type": "string", "minLength": 3},
+			"examples": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items": map[string]interface{}{
+					"type":                 "object",
+					"required":             []string{"arabic"},
+					"additionalProperties": false,
+					"properties": map[string]interface{}{
+						"arabic":      map[string]interface{}{"type": "string", "minLength": 1},
+						"translation": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		// Substring of the expected error, empty if the input is valid
+		err string
+	}{
+		{input: `{"title": "كتب", "examples": [{"arabic": "كتب الولد", "translation": "the boy wrote"}]}`},
+		{input: "```json\n{\"title\": \"كتب\", \"examples\": [{\"arabic\": \"كتب\"}]}\n```"},
+		{input: `{"title": "كتب"}`, err: "$.examples is missing"},
+		{input: `{"title": "كتب", "examples": []}`, err: "$.examples should have at least 1 items, got 0"},
+		{input: `{"title": "كتب", "examples": {}}`, err: "$.examples should be an array"},
+		{input: `{"title": 3, "examples": [{"arabic": "كتب"}]}`, err: "$.title should be a string"},
+		{input: `{"title": "كت", "examples": [{"arabic": "كتب"}]}`, err: "$.title should have at least 3 characters, got 2"},
+		{input: `{"title": "كتب", "examples": [{"arabic": "  "}]}`, err: "$.examples[0].arabic should have at least 1 characters, got 0"},
+		{input: `{"title": "كتب", "examples": [{"arabic": "كتب", "notes": "x"}]}`, err: "$.examples[0].notes is not an allowed property"},
+		{input: `{"title": "كتب", "examples": [{"arabic": "كتب"}], "extra": 1}`, err: "$.extra is not an allowed property"},
+		{input: `["كتب"]`, err: "invalid JSON"},
+	}
+
+	for _, test := range tests {
+		var out map[string]interface{}
+		err := decodeJSON(test.input, testSchema, &out)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("decodeJSON(%q): %s", test.input, err)
+		case test.err != "" && err == nil:
+			t.Errorf("decodeJSON(%q) should fail with %q", test.input, test.err)
+		case test.err != "" && !strings.Contains(err.Error(), test.err):
+			t.Errorf("decodeJSON(%q) = %q, want %q", test.input, err, test.err)
+		}
+	}
+}
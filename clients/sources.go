@@ -0,0 +1,147 @@
+package clients
+
+import (
+	"fmt"
+	"maps"
+	"sahib/model"
+	"slices"
+)
+
+type QueryFunc func(word string, lang model.Language) (*model.Translations, error)
+
+// SourceConfig holds what the remote sources need to be queried.
+type SourceConfig struct {
+	PerplexityApiKey string
+	// Generic LLM source, nil if not configured
+	LLM *LLMClient
+	// Records the usage of the LLM sources, can be nil
+	Usage UsageRecorder
+	// Name of the user the usage is recorded for
+	User string
+	// Local wiktionary, nil if not imported
+	Wiktionary *Wiktionary
+	// Local classical dictionary, nil if not imported
+	Lane *Lane
+	// Local example sentences, nil if not imported
+	Tatoeba *Tatoeba
+	// Imported local dictionaries by source name
+	Local map[string]QueryFunc
+}
+
+// Source returns the query function of a remote source from its name.
+func (c SourceConfig) Source(name string) (QueryFunc, error) {
+	var fn QueryFunc
+	switch name {
+	case model.SourceElixir:
+		fn = QueryElixir
+	case model.SourceMaany:
+		fn = QueryMaany
+	case model.SourceMaanyArabic:
+		fn = QueryMaanyArabic
+	case model.SourceWiktionary:
+		if c.Wiktionary == nil {
+			return noResults, nil
+		}
+		fn = c.Wiktionary.Query
+	case model.SourceLane:
+		if c.Lane == nil {
+			return noResults, nil
+		}
+		fn = c.Lane.Query
+	case model.SourceTatoeba:
+		if c.Tatoeba == nil {
+			return noResults, nil
+		}
+		fn = c.Tatoeba.Query
+	case model.SourcePerplexity:
+		if c.PerplexityApiKey == "" {
+			return noResults, nil
+		}
+		fn = Perplexity(c.PerplexityApiKey).WithUsage(c.Usage, c.User).Query
+	case model.SourceLLM:
+		if c.LLM == nil {
+			return noResults, nil
+		}
+		fn = c.LLM.WithUsage(c.Usage, c.User).Query
+	default:
+		local, ok := c.Local[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source: %s", name)
+		}
+		fn = local
+	}
+
+	return fn, nil
+}
+
+// Available returns whether a source is configured and can be queried.
+func (c SourceConfig) Available(name string) bool {
+	switch name {
+	case model.SourcePerplexity:
+		return c.PerplexityApiKey != ""
+	case model.SourceLLM:
+		return c.LLM != nil
+	case model.SourceWiktionary:
+		return c.Wiktionary != nil
+	case model.SourceLane:
+		return c.Lane != nil
+	case model.SourceTatoeba:
+		return c.Tatoeba != nil
+	}
+
+	return true
+}
+
+// LLMSource returns the client behind a source if it is a configured LLM one,
+// their answers can be streamed.
+func (c SourceConfig) LLMSource(name string) *LLMClient {
+	switch name {
+	case model.SourcePerplexity:
+		if c.PerplexityApiKey != "" {
+			return Perplexity(c.PerplexityApiKey).WithUsage(c.Usage, c.User)
+		}
+	case model.SourceLLM:
+		if c.LLM != nil {
+			return c.LLM.WithUsage(c.Usage, c.User)
+		}
+	}
+
+	return nil
+}
+
+// maanyLanguages are the codes of the target languages of the almaany dictionaries.
+var maanyLanguages = []string{"fr", "en", "es", "de", "tr", "it", "pt", "ru", "id", "ms", "fa", "ur"}
+
+// SupportedLanguages returns the codes of the languages a source can answer
+// in, nil if it supports them all. Hans Wehr and Elixir are supported with
+// every language since their answer doesn't depend on it.
+func SupportedLanguages(source string) []string {
+	switch source {
+	case model.SourceMaany:
+		return maanyLanguages
+	case model.SourceTatoeba:
+		return slices.Sorted(maps.Keys(tatoebaLanguages))
+	}
+
+	return nil
+}
+
+// Supports returns whether the source can answer in the language.
+func Supports(source string, lang model.Language) bool {
+	codes := SupportedLanguages(source)
+	if codes == nil {
+		return true
+	}
+
+	for _, code := range codes {
+		if code == lang.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// noResults is used for the sources that aren't configured.
+func noResults(word string, lang model.Language) (*model.Translations, error) {
+	return &model.Translations{}, nil
+}
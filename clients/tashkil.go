@@ -5,15 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sahib/model"
 	"strings"
 )
 
-func tashkil(sentences []string) ([]string, error) {
+// TashkilDiacritizer vocalizes sentences using tashkil.net
+type TashkilDiacritizer struct{}
+
+func (TashkilDiacritizer) Diacritize(sentences []string, lang model.Language) ([]string, error) {
+	return tashkil(sentences, lang)
+}
+
+func tashkil(sentences []string, lang model.Language) ([]string, error) {
 	url := "https://www.tashkil.net/api/openai/tashkil"
 
 	requestBody := map[string]interface{}{
 		"hasPremium":     false,
-		"targetLanguage": "French",
+		"targetLanguage": lang.Name,
 		"messages": []map[string]interface{}{
 			{
 				"role":    "user",
@@ -30,7 +38,7 @@ func tashkil(sentences []string) ([]string, error) {
 
 	rawResp, err := queryURL("POST", url, bytes.NewBuffer(jsonBody), nil, false)
 	if err != nil {
-		return sentences, fmt.Errorf("Error sending perplexity request: %w\n", err)
+		return sentences, fmt.Errorf("Error sending tashkil request: %w\n", err)
 	}
 	defer rawResp.Body.Close()
 
@@ -40,10 +48,5 @@ func tashkil(sentences []string) ([]string, error) {
 		return sentences, fmt.Errorf("Error reading body: %w\n", err)
 	}
 
-	out := strings.Split(string(body), "\n")
-	if len(out) != len(sentences) {
-		return sentences, fmt.Errorf("wrong number of sentences, expected %d got %d", len(sentences), len(out))
-	}
-
-	return out, nil
+	return align(sentences, strings.Split(string(body), "\n")), nil
 }
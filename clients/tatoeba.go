@@ -0,0 +1,105 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+	"sahib/model"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const TatoebaURL = "https://tatoeba.org"
+
+// maxTatoebaSentences is the number of example sentences returned for a word.
+const maxTatoebaSentences = 20
+
+// Tatoeba queries the example sentences imported with ImportTatoeba.
+type Tatoeba struct {
+	db *sql.DB
+}
+
+func NewTatoebaClient(path string) (*Tatoeba, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to init tatoeba client: %w", err)
+	}
+
+	return &Tatoeba{db: db}, nil
+}
+
+func (t *Tatoeba) Close() error {
+	return t.db.Close()
+}
+
+// Query returns the sentences containing the word, with or without clitics,
+// and their translation in the language. The word is stripped of its own
+// clitics if no sentence has it. The shortest sentences with a translation
+// come first.
+func (t *Tatoeba) Query(word string, lang model.Language) (*model.Translations, error) {
+	result := &model.Translations{Link: TatoebaURL}
+	start := time.Now()
+	defer func() {
+		result.Elapsed = Elapsed(start)
+	}()
+
+	for _, candidate := range Candidates(word) {
+		// The terms are quoted not to be read as FTS operators
+		term := `"` + strings.ReplaceAll(normalizeKey(candidate), `"`, "") + `"`
+		rows, err := t.sentences(term, tatoebaLanguages[lang.Code])
+		if err != nil {
+			return result, err
+		}
+		if len(rows) > 0 {
+			result.List = rows
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (t *Tatoeba) sentences(match string, lang string) ([]model.Translation, error) {
+	rows, err := t.db.Query(`SELECT s.text,
+            (SELECT tr.text FROM tatoeba_links l JOIN tatoeba_sentences tr ON tr.id = l.translation_id
+             WHERE l.arabic_id = s.id AND tr.lang = ? LIMIT 1) AS translation
+        FROM tatoeba_sentences s
+        WHERE s.id IN (SELECT docid FROM tatoeba_fts WHERE terms MATCH ?)
+        ORDER BY translation IS NULL, length(s.text)
+        LIMIT ?`, lang, match, maxTatoebaSentences)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tatoeba: %w", err)
+	}
+	defer rows.Close()
+
+	out := []model.Translation{}
+	for rows.Next() {
+		var text string
+		var translation sql.NullString
+		if err := rows.Scan(&text, &translation); err != nil {
+			return nil, fmt.Errorf("error while scanning tatoeba sentence: %w", err)
+		}
+		out = append(out, model.Translation{Arabic: text, Translation: translation.String})
+	}
+
+	return out, rows.Err()
+}
+
+// EachArabicSentence calls fn with the text of every arabic sentence.
+func (t *Tatoeba) EachArabicSentence(fn func(text string)) error {
+	rows, err := t.db.Query(`SELECT text FROM tatoeba_sentences WHERE lang = 'ara'`)
+	if err != nil {
+		return fmt.Errorf("failed to read the tatoeba sentences: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return fmt.Errorf("error while scanning tatoeba sentence: %w", err)
+		}
+		fn(text)
+	}
+	return rows.Err()
+}
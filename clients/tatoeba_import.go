@@ -0,0 +1,179 @@
+package clients
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// tatoebaLanguages maps the codes of the translation languages to the ISO
+// 639-3 codes used by tatoeba.
+var tatoebaLanguages = map[string]string{
+	"fr": "fra", "en": "eng", "es": "spa", "de": "deu", "tr": "tur",
+	"it": "ita", "pt": "por", "ru": "rus", "id": "ind", "ms": "zsm", "fa": "pes",
+	"ur": "urd",
+}
+
+// tatoebaTerms returns the indexed terms of an arabic sentence: its words and
+// the stems obtained by stripping their clitics.
+func tatoebaTerms(text string) string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, token := range Tokenize(text) {
+		if !token.Word {
+			continue
+		}
+		for _, candidate := range Candidates(token.Text) {
+			term := normalizeKey(candidate)
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// tatoebaLines calls fn with the tab separated fields of every line.
+func tatoebaLines(r io.Reader, fn func(line int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if err := fn(line, strings.Split(scanner.Text(), "\t")); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ImportTatoeba (re)creates the tatoeba tables of the database from exports
+// of the sentences (id, lang, text) and of the links between them (id,
+// translation id). Only the arabic sentences and their translations in the
+// known languages are kept. It returns the number of arabic sentences and of
+// translations.
+func ImportTatoeba(db *sql.DB, sentences []io.Reader, links io.Reader) (int, int, error) {
+	schema := []string{
+		`DROP TABLE IF EXISTS tatoeba_fts`,
+		`DROP TABLE IF EXISTS tatoeba_links`,
+		`DROP TABLE IF EXISTS tatoeba_sentences`,
+		`CREATE TABLE tatoeba_sentences (
+            id INTEGER PRIMARY KEY,
+            lang TEXT NOT NULL,
+            text TEXT NOT NULL
+        )`,
+		`CREATE TABLE tatoeba_links (
+            arabic_id INTEGER NOT NULL,
+            translation_id INTEGER NOT NULL
+        )`,
+		`CREATE VIRTUAL TABLE tatoeba_fts USING fts4(terms)`,
+	}
+
+	// The previous tables are kept if the import fails
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range schema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, 0, fmt.Errorf("failed to create the tatoeba tables: %w", err)
+		}
+	}
+
+	known := map[string]bool{"ara": true}
+	for _, code := range tatoebaLanguages {
+		known[code] = true
+	}
+
+	insertSentence, err := tx.Prepare(`INSERT OR REPLACE INTO tatoeba_sentences (id, lang, text) VALUES (?, ?, ?)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insertSentence.Close()
+
+	insertTerms, err := tx.Prepare(`INSERT INTO tatoeba_fts (docid, terms) VALUES (?, ?)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insertTerms.Close()
+
+	arabic := map[int64]bool{}
+	for _, r := range sentences {
+		err := tatoebaLines(r, func(line int, fields []string) error {
+			if len(fields) < 3 || !known[fields[1]] {
+				return nil
+			}
+			id, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid sentence id at line %d: %w", line, err)
+			}
+
+			if _, err := insertSentence.Exec(id, fields[1], fields[2]); err != nil {
+				return fmt.Errorf("failed to insert sentence %d: %w", id, err)
+			}
+			if fields[1] == "ara" && !arabic[id] {
+				arabic[id] = true
+				if _, err := insertTerms.Exec(id, tatoebaTerms(fields[2])); err != nil {
+					return fmt.Errorf("failed to index sentence %d: %w", id, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read the sentences: %w", err)
+		}
+	}
+	log.Printf("Imported %d arabic sentences", len(arabic))
+
+	insertLink, err := tx.Prepare(`INSERT INTO tatoeba_links (arabic_id, translation_id) VALUES (?, ?)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insertLink.Close()
+
+	// The links go both ways, only keep the ones from the arabic sentences
+	err = tatoebaLines(links, func(line int, fields []string) error {
+		if len(fields) < 2 {
+			return nil
+		}
+		id, err1 := strconv.ParseInt(fields[0], 10, 64)
+		translation, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("invalid link at line %d", line)
+		}
+		if !arabic[id] || arabic[translation] {
+			return nil
+		}
+
+		if _, err := insertLink.Exec(id, translation); err != nil {
+			return fmt.Errorf("failed to insert link %d-%d: %w", id, translation, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read the links: %w", err)
+	}
+
+	cleanup := []string{
+		`DELETE FROM tatoeba_links WHERE translation_id NOT IN (SELECT id FROM tatoeba_sentences)`,
+		`DELETE FROM tatoeba_sentences WHERE lang != 'ara' AND id NOT IN (SELECT translation_id FROM tatoeba_links)`,
+		`CREATE INDEX tatoeba_links_arabic ON tatoeba_links(arabic_id)`,
+	}
+	for _, stmt := range cleanup {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, 0, fmt.Errorf("failed to clean the translations: %w", err)
+		}
+	}
+
+	var translations int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM tatoeba_sentences WHERE lang != 'ara'`).Scan(&translations); err != nil {
+		return 0, 0, err
+	}
+
+	return len(arabic), translations, tx.Commit()
+}
@@ -0,0 +1,156 @@
+package clients
+
+import (
+	"database/sql"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+const testTatoebaSentences = "1\tara\tقرأت الكتاب.\n" +
+	"2\teng\tI read the book.\n" +
+	"3\tfra\tJ'ai lu le livre.\n" +
+	"4\tara\tهذا كتابي وكتابك.\n" +
+	"5\teng\tThis is my book and your book.\n" +
+	"6\tjpn\t本を読んだ。\n" +
+	"7\tara\tكتاب\n"
+
+const testTatoebaLinks = "1\t2\n2\t1\n1\t3\n1\t6\n4\t5\n1\t4\n1\t99\n"
+
+func newTestTatoeba(t *testing.T) *Tatoeba {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tatoeba.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	arabic, translations, err := ImportTatoeba(db, []io.Reader{strings.NewReader(testTatoebaSentences)}, strings.NewReader(testTatoebaLinks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The japanese sentence isn't kept, nor the english one without link
+	if arabic != 3 || translations != 3 {
+		t.Errorf("ImportTatoeba() = %d, %d, want 3 arabic sentences and 3 translations", arabic, translations)
+	}
+
+	tatoeba, err := NewTatoebaClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tatoeba.Close() })
+	return tatoeba
+}
+
+func TestTatoebaQuery(t *testing.T) {
+	tatoeba := newTestTatoeba(t)
+	french := model.Language{Short: "lang_fr", Code: "fr", Name: "French"}
+
+	tests := []struct {
+		name string
+		word string
+		lang model.Language
+		want []model.Translation
+	}{
+		{
+			name: "translated sentences first",
+			word: "كتاب",
+			lang: testLanguage,
+			want: []model.Translation{
+				{Arabic: "قرأت الكتاب.", Translation: "I read the book."},
+				{Arabic: "هذا كتابي وكتابك.", Translation: "This is my book and your book."},
+				{Arabic: "كتاب"},
+			},
+		},
+		{
+			name: "other language",
+			word: "كتاب",
+			lang: french,
+			want: []model.Translation{
+				{Arabic: "قرأت الكتاب.", Translation: "J'ai lu le livre."},
+				{Arabic: "كتاب"},
+				{Arabic: "هذا كتابي وكتابك."},
+			},
+		},
+		{
+			name: "word found as is",
+			word: "الكتاب",
+			lang: testLanguage,
+			want: []model.Translation{{Arabic: "قرأت الكتاب.", Translation: "I read the book."}},
+		},
+		{
+			name: "clitics of the word",
+			word: "وكتابك",
+			lang: testLanguage,
+			want: []model.Translation{{Arabic: "هذا كتابي وكتابك.", Translation: "This is my book and your book."}},
+		},
+		{name: "unknown", word: "قلم", lang: testLanguage},
+		{name: "fts operator", word: `"OR`, lang: testLanguage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := tatoeba.Query(test.word, test.lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.List, test.want) {
+				t.Errorf("Query(%q) = %+v, want %+v", test.word, res.List, test.want)
+			}
+		})
+	}
+}
+
+func TestTatoebaSupportedLanguages(t *testing.T) {
+	codes := SupportedLanguages(model.SourceTatoeba)
+	for _, lang := range model.AllLanguages() {
+		_, ok := tatoebaLanguages[lang.Code]
+		if supported := Supports(model.SourceTatoeba, lang); supported != ok {
+			t.Errorf("Supports(Tatoeba, %s) = %v, want %v", lang.Code, supported, ok)
+		}
+	}
+	if len(codes) != len(tatoebaLanguages) {
+		t.Errorf("SupportedLanguages(Tatoeba) = %v", codes)
+	}
+}
+
+func TestImportTatoebaInvalidLinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tatoeba.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, _, err := ImportTatoeba(db, []io.Reader{strings.NewReader(testTatoebaSentences)}, strings.NewReader(testTatoebaLinks)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sentences are valid but the links were exported with a header
+	sentences := "8\tara\tأين القلم؟\n9\teng\tWhere is the pen?\n"
+	links := "sentence_id\ttranslation_id\n8\t9\n"
+	_, _, err = ImportTatoeba(db, []io.Reader{strings.NewReader(sentences)}, strings.NewReader(links))
+	if err == nil || !strings.Contains(err.Error(), "invalid link at line 1") {
+		t.Fatalf("ImportTatoeba() = %v, want an error for the header", err)
+	}
+
+	tatoeba, err := NewTatoebaClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tatoeba.Close()
+	for word, want := range map[string]int{"كتاب": 3, "القلم": 0} {
+		res, err := tatoeba.Query(word, testLanguage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.List) != want {
+			t.Errorf("Query(%q) = %+v, want the %d sentences of the previous import", word, res.List, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sahib/model"
+	"unicode/utf8"
+)
+
+// UsageRecorder keeps track of the tokens consumed by the LLM requests.
+type UsageRecorder interface {
+	RecordUsage(usage model.LLMUsage) error
+	// CheckBudget returns an error if the user can't make more requests.
+	CheckBudget(user string) error
+}
+
+// chatUsage is the usage block of the chat/completions responses.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (c *LLMClient) recordUsage(usage *chatUsage) {
+	if c.Usage == nil || usage == nil {
+		return
+	}
+
+	err := c.Usage.RecordUsage(model.LLMUsage{
+		Provider:         c.Name,
+		Model:            c.Model,
+		User:             c.User,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+	if err != nil {
+		log.Printf("Failed to record %s usage: %s", c.Name, err)
+	}
+}
+
+// charsPerToken is a rough average for the arabic and latin texts of the
+// prompts, the tokenizers of the models differ.
+const charsPerToken = 3
+
+// estimateUsage approximates the tokens of a request whose usage wasn't
+// reported by the provider.
+func estimateUsage(messages []ChatMessage, completion string) *chatUsage {
+	prompt := 0
+	for _, m := range messages {
+		prompt += utf8.RuneCountInString(m.Content)
+	}
+
+	return &chatUsage{
+		PromptTokens:     (prompt + charsPerToken - 1) / charsPerToken,
+		CompletionTokens: (utf8.RuneCountInString(completion) + charsPerToken - 1) / charsPerToken,
+	}
+}
+
+// Price of a model in dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+func (p Price) Cost(promptTokens int, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// DefaultPrices returns the prices of the models of the presets, the local
+// models are free.
+func DefaultPrices() map[string]Price {
+	return map[string]Price{
+		"sonar":               {Input: 1, Output: 1},
+		"sonar-pro":           {Input: 3, Output: 15},
+		"sonar-reasoning":     {Input: 1, Output: 5},
+		"sonar-reasoning-pro": {Input: 2, Output: 8},
+		"gpt-4o":              {Input: 2.5, Output: 10},
+		"gpt-4o-mini":         {Input: 0.15, Output: 0.6},
+		"gpt-4.1":             {Input: 2, Output: 8},
+		"gpt-4.1-mini":        {Input: 0.4, Output: 1.6},
+	}
+}
+
+// LoadPrices returns the default prices overridden by the ones of a JSON file
+// mapping the model names to their price, e.g. {"sonar-pro": {"input": 3, "output": 15}}.
+func LoadPrices(path string) (map[string]Price, error) {
+	prices := DefaultPrices()
+	if path == "" {
+		return prices, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prices: %w", err)
+	}
+
+	custom := map[string]Price{}
+	if err := json.Unmarshal(content, &custom); err != nil {
+		return nil, fmt.Errorf("invalid prices file %s: %w", path, err)
+	}
+
+	for name, price := range custom {
+		prices[name] = price
+	}
+
+	return prices, nil
+}
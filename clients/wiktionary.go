@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sahib/model"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Wiktionary queries the arabic entries of a wiktionary dump imported with
+// ImportWiktionary.
+type Wiktionary struct {
+	db *sql.DB
+}
+
+func NewWiktionaryClient(path string) (*Wiktionary, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to init wiktionary client: %w", err)
+	}
+
+	return &Wiktionary{db: db}, nil
+}
+
+func (w *Wiktionary) Close() error {
+	return w.db.Close()
+}
+
+// normalizeKey is how the headwords, roots and lemmas are indexed.
+func normalizeKey(word string) string {
+	return NormalizeAlef(strings.ReplaceAll(Normalize(word), " ", ""))
+}
+
+// Query returns the entries whose headword, root or lemma is the word, the
+// headwords first.
+func (w *Wiktionary) Query(word string, ignored model.Language) (*model.Translations, error) {
+	result := &model.Translations{}
+	start := time.Now()
+	defer func() {
+		result.Elapsed = Elapsed(start)
+	}()
+
+	key := normalizeKey(word)
+	if key == "" {
+		// The lemmas themselves have an empty lemma
+		return result, nil
+	}
+
+	rows, err := w.db.Query(`SELECT entry FROM wiktionary
+        WHERE normalized = ? OR root = ? OR lemma = ?
+        ORDER BY normalized = ? DESC, lemma = ? DESC, id
+        LIMIT 50`, key, key, key, key, key)
+	if err != nil {
+		return result, fmt.Errorf("failed to query wiktionary: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return result, fmt.Errorf("error while scanning wiktionary entry: %w", err)
+		}
+
+		entry := model.WiktionaryEntry{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return result, fmt.Errorf("invalid wiktionary entry: %w", err)
+		}
+
+		glosses := make([]string, 0, len(entry.Senses))
+		for _, sense := range entry.Senses {
+			glosses = append(glosses, sense.Gloss)
+		}
+
+		result.Wiktionary = append(result.Wiktionary, entry)
+		result.List = append(result.List, model.Translation{
+			Arabic:      entry.Word,
+			Translation: strings.Join(glosses, "; "),
+			Meta:        entry.POS,
+		})
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,194 @@
+package clients
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sahib/model"
+	"strings"
+)
+
+// kaikkiEntry is the part of the kaikki.org JSONL extracts of the wiktionary
+// that is imported.
+type kaikkiEntry struct {
+	Word               string `json:"word"`
+	POS                string `json:"pos"`
+	LangCode           string `json:"lang_code"`
+	EtymologyText      string `json:"etymology_text"`
+	EtymologyTemplates []struct {
+		Name string            `json:"name"`
+		Args map[string]string `json:"args"`
+	} `json:"etymology_templates"`
+	Sounds []struct {
+		IPA string `json:"ipa"`
+	} `json:"sounds"`
+	Forms []struct {
+		Form string   `json:"form"`
+		Tags []string `json:"tags"`
+	} `json:"forms"`
+	Senses []struct {
+		Glosses  []string `json:"glosses"`
+		Tags     []string `json:"tags"`
+		Examples []struct {
+			Text    string `json:"text"`
+			English string `json:"english"`
+		} `json:"examples"`
+		FormOf []struct {
+			Word string `json:"word"`
+		} `json:"form_of"`
+	} `json:"senses"`
+}
+
+func (k kaikkiEntry) entry() model.WiktionaryEntry {
+	entry := model.WiktionaryEntry{Word: k.Word, POS: k.POS, Etymology: k.EtymologyText}
+
+	for _, t := range k.EtymologyTemplates {
+		if t.Name != "ar-root" {
+			continue
+		}
+		letters := []string{}
+		for _, arg := range []string{"1", "2", "3", "4"} {
+			if l := t.Args[arg]; l != "" {
+				letters = append(letters, l)
+			}
+		}
+		entry.Root = strings.Join(letters, " ")
+		break
+	}
+
+	for _, s := range k.Sounds {
+		if s.IPA != "" {
+			entry.Pronunciation = s.IPA
+			break
+		}
+	}
+
+	for _, f := range k.Forms {
+		if hasTag(f.Tags, "romanization") {
+			if entry.Romanization == "" {
+				entry.Romanization = f.Form
+			}
+			continue
+		}
+		// Only the forms in arabic script, not the inflection table headers
+		if !strings.ContainsFunc(f.Form, isArabic) || hasTag(f.Tags, "table-tags") || hasTag(f.Tags, "inflection-template") {
+			continue
+		}
+		entry.Forms = append(entry.Forms, model.WiktionaryForm{Form: f.Form, Tags: f.Tags})
+	}
+
+	for _, s := range k.Senses {
+		if len(s.Glosses) == 0 {
+			continue
+		}
+		sense := model.WiktionarySense{Gloss: strings.Join(s.Glosses, ": "), Tags: s.Tags}
+		for _, e := range s.Examples {
+			sense.Examples = append(sense.Examples, model.Translation{Arabic: e.Text, Translation: e.English})
+		}
+		entry.Senses = append(entry.Senses, sense)
+
+		if entry.Lemma == "" && len(s.FormOf) > 0 {
+			entry.Lemma = s.FormOf[0].Word
+		}
+	}
+
+	return entry
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportWiktionary (re)creates the wiktionary table of the database from a
+// kaikki.org JSONL extract, only the arabic entries are kept. It returns the
+// number of imported entries.
+func ImportWiktionary(db *sql.DB, r io.Reader) (int, error) {
+	schema := []string{
+		`DROP TABLE IF EXISTS wiktionary`,
+		`CREATE TABLE wiktionary (
+            id INTEGER PRIMARY KEY,
+            word TEXT NOT NULL,
+            normalized TEXT NOT NULL,
+            root TEXT NOT NULL,
+            lemma TEXT NOT NULL,
+            entry TEXT NOT NULL
+        )`,
+	}
+
+	// The previous entries are kept if the import fails
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range schema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, fmt.Errorf("failed to create the wiktionary table: %w", err)
+		}
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO wiktionary (word, normalized, root, lemma, entry) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(r)
+	// Some entries (with their inflection tables) are very long
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		k := kaikkiEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &k); err != nil {
+			return count, fmt.Errorf("invalid entry at line %d: %w", line, err)
+		}
+		if k.LangCode != "ar" || k.Word == "" {
+			continue
+		}
+
+		entry := k.entry()
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return count, err
+		}
+
+		lemma := ""
+		if entry.Lemma != "" {
+			lemma = normalizeKey(entry.Lemma)
+		}
+		_, err = insert.Exec(entry.Word, normalizeKey(entry.Word), normalizeKey(entry.Root), lemma, string(raw))
+		if err != nil {
+			return count, fmt.Errorf("failed to insert %s: %w", entry.Word, err)
+		}
+
+		count++
+		if count%10000 == 0 {
+			log.Printf("Imported %d wiktionary entries", count)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read the extract: %w", err)
+	}
+
+	indexes := []string{
+		`CREATE INDEX wiktionary_normalized ON wiktionary(normalized)`,
+		`CREATE INDEX wiktionary_root ON wiktionary(root)`,
+		`CREATE INDEX wiktionary_lemma ON wiktionary(lemma)`,
+	}
+	for _, stmt := range indexes {
+		if _, err := tx.Exec(stmt); err != nil {
+			return count, fmt.Errorf("failed to index the wiktionary: %w", err)
+		}
+	}
+
+	return count, tx.Commit()
+}
@@ -0,0 +1,136 @@
+package clients
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+const testKaikki = `{"word": "كِتَاب", "pos": "noun", "lang_code": "ar", "etymology_templates": [{"name": "ar-root", "args": {"1": "ك", "2": "ت", "3": "ب"}}], "sounds": [{"ipa": "/ki.taːb/"}], "forms": [{"form": "kitāb", "tags": ["romanization"]}, {"form": "كُتُب", "tags": ["plural"]}, {"form": "no-table-tags", "tags": ["table-tags"]}], "senses": [{"glosses": ["book"], "examples": [{"text": "هٰذَا كِتَاب", "english": "this is a book"}]}]}
+{"word": "كُتُب", "pos": "noun", "lang_code": "ar", "senses": [{"glosses": ["plural of كِتَاب"], "tags": ["form-of", "plural"], "form_of": [{"word": "كِتَاب"}]}]}
+{"word": "كَتَبَ", "pos": "verb", "lang_code": "ar", "etymology_templates": [{"name": "ar-root", "args": {"1": "ك", "2": "ت", "3": "ب"}}], "senses": [{"glosses": ["to write"]}]}
+{"word": "book", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["كتاب"]}]}
+`
+
+var testLanguage = model.Language{Short: "lang_en", Code: "en", Name: "English"}
+
+func newTestWiktionary(t *testing.T, extract string) (*Wiktionary, int) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "wiktionary.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	count, err := ImportWiktionary(db, strings.NewReader(extract))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWiktionaryClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, count
+}
+
+func TestImportWiktionary(t *testing.T) {
+	w, count := newTestWiktionary(t, testKaikki)
+	if count != 3 {
+		t.Errorf("imported %d entries, want the 3 arabic ones", count)
+	}
+
+	tests := []struct {
+		name  string
+		word  string
+		words []string
+	}{
+		{"headword then lemma", "كتاب", []string{"كِتَاب", "كُتُب"}},
+		// The root has the letters of the plural and of the verb
+		{"headwords then root", "ك ت ب", []string{"كُتُب", "كَتَبَ", "كِتَاب"}},
+		{"unknown", "قلم", nil},
+		{"empty key", "؟", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := w.Query(test.word, testLanguage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var words []string
+			for _, entry := range res.Wiktionary {
+				words = append(words, entry.Word)
+			}
+			if !reflect.DeepEqual(words, test.words) {
+				t.Errorf("Query(%q) = %v, want %v", test.word, words, test.words)
+			}
+		})
+	}
+}
+
+func TestImportWiktionaryEntry(t *testing.T) {
+	w, _ := newTestWiktionary(t, testKaikki)
+
+	res, err := w.Query("كتاب", testLanguage)
+	if err != nil || len(res.Wiktionary) == 0 {
+		t.Fatalf("Query() = %+v, %v", res, err)
+	}
+
+	entry := res.Wiktionary[0]
+	if entry.Root != "ك ت ب" || entry.Pronunciation != "/ki.taːb/" || entry.Romanization != "kitāb" {
+		t.Errorf("root %q, pronunciation %q, romanization %q", entry.Root, entry.Pronunciation, entry.Romanization)
+	}
+	if len(entry.Forms) != 1 || entry.Forms[0].Form != "كُتُب" {
+		t.Errorf("forms = %+v, want the plural only", entry.Forms)
+	}
+	if len(entry.Senses) != 1 || len(entry.Senses[0].Examples) != 1 || entry.Senses[0].Examples[0].Translation != "this is a book" {
+		t.Errorf("senses = %+v", entry.Senses)
+	}
+	if lemma := res.Wiktionary[1].Lemma; lemma != "كِتَاب" {
+		t.Errorf("lemma of the plural = %q", lemma)
+	}
+}
+
+func TestImportWiktionaryTruncatedExtract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wiktionary.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := ImportWiktionary(db, strings.NewReader(testKaikki)); err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted download of a newer extract
+	truncated := `{"word": "قَلَم", "pos": "noun", "lang_code": "ar", "senses": [{"glosses": ["pen"]}]}
+{"word": "دَفْتَر", "pos": "noun", "lang_co`
+	_, err = ImportWiktionary(db, strings.NewReader(truncated))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("ImportWiktionary() = %v, want an error at line 2", err)
+	}
+
+	w, err := NewWiktionaryClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for word, want := range map[string]int{"كتاب": 2, "قلم": 0} {
+		res, err := w.Query(word, testLanguage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Wiktionary) != want {
+			t.Errorf("Query(%q) = %d entries, want %d of the previous extract", word, len(res.Wiktionary), want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sahib/dictionary"
+	"strings"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Imports a StarDict (.ifo), XDXF (.xdxf) or CSV/TSV (.csv, .tsv) dictionary.
+func main() {
+	db := flag.String("db", "assets/dictionaries.sqlite", "path to the dictionaries sqlite database")
+	name := flag.String("name", "", "name of the dictionary, shown as a source (defaults to the file name)")
+	list := flag.Bool("list", false, "list the imported dictionaries")
+	remove := flag.String("delete", "", "name of a dictionary to delete")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <dictionary.ifo|.xdxf|.csv|.tsv>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	store, err := dictionary.Open(*db)
+	failIf(err)
+	defer store.Close()
+
+	switch {
+	case *list:
+		dicts, err := store.Dictionaries()
+		failIf(err)
+		for _, d := range dicts {
+			fmt.Printf("%s (%s): %d entries\n", d.Name, d.Format, d.Entries)
+		}
+		return
+	case *remove != "":
+		failIf(store.Delete(*remove))
+		return
+	case flag.NArg() != 1:
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	path := flag.Arg(0)
+	ext := strings.ToLower(filepath.Ext(path))
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	var entries []dictionary.Entry
+	format := strings.TrimPrefix(ext, ".")
+	switch ext {
+	case ".ifo":
+		format = "stardict"
+		entries, err = dictionary.ReadStarDict(path)
+	case ".xdxf", ".csv", ".tsv":
+		f, ferr := os.Open(path)
+		failIf(ferr)
+		defer f.Close()
+
+		switch ext {
+		case ".xdxf":
+			entries, err = dictionary.ReadXDXF(f)
+		case ".csv":
+			entries, err = dictionary.ReadCSV(f, ',')
+		case ".tsv":
+			entries, err = dictionary.ReadCSV(f, '\t')
+		}
+	default:
+		failIf(fmt.Errorf("unsupported dictionary format: %s", ext))
+	}
+	failIf(err)
+
+	failIf(store.Import(*name, format, entries))
+	log.Printf("Imported %d entries into %s", len(entries), *name)
+}
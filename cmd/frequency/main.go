@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sahib/clients"
+	"sahib/frequency"
+	"sahib/glossary"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Imports a frequency list (a word per line, most frequent first, optionally
+// followed by its count) or computes one from documents (.txt, .srt, .html,
+// .epub) and from the imported tatoeba sentences.
+func main() {
+	db := flag.String("db", "assets/frequency.sqlite", "path to the sqlite database to create")
+	list := flag.String("list", "", "frequency list to import, e.g. ar_50k.txt")
+	tatoeba := flag.String("tatoeba", "", "tatoeba database (see cmd/tatoeba) whose sentences are counted")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [document]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var words []frequency.WordCount
+	if *list != "" {
+		if flag.NArg() > 0 || *tatoeba != "" {
+			failIf(fmt.Errorf("either import a list or count the words of a corpus"))
+		}
+
+		f, err := os.Open(*list)
+		failIf(err)
+		words, err = frequency.ReadList(f)
+		f.Close()
+		failIf(err)
+	} else {
+		counts := map[string]int{}
+		for _, path := range flag.Args() {
+			content, err := os.ReadFile(path)
+			failIf(err)
+			text, err := glossary.ExtractText(path, content)
+			failIf(err)
+			frequency.Count(counts, text)
+		}
+
+		if *tatoeba != "" {
+			t, err := clients.NewTatoebaClient(*tatoeba)
+			failIf(err)
+			failIf(t.EachArabicSentence(func(text string) {
+				frequency.Count(counts, text)
+			}))
+			t.Close()
+		}
+
+		if len(counts) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		words = frequency.Sorted(counts)
+	}
+
+	conn, err := sql.Open("sqlite3", *db)
+	failIf(err)
+	defer conn.Close()
+
+	failIf(frequency.Import(conn, words))
+	log.Printf("Imported %d words into %s", len(words), *db)
+}
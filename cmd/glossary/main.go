@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/frequency"
+	"sahib/glossary"
+	"sahib/model"
+	"strings"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	db := flag.String("db", "assets/hanswehr.sqlite", "path to the hans wehr sqlite database")
+	top := flag.Int("top", 50, "number of unknown words to put in the glossary")
+	format := flag.String("format", glossary.FormatCSV, "output format: "+strings.Join(glossary.Formats, ", "))
+	sources := flag.String("sources", "", "comma separated list of remote sources to query (e.g. Elixir,Maany)")
+	lang := flag.String("lang", "fr", "translation language")
+	vocabularyPath := flag.String("vocabulary", "", "file with the known words, one per line")
+	output := flag.String("o", "", "output file (defaults to stdout)")
+	frequencyPath := flag.String("frequency", "", "frequency list (see cmd/frequency) to rank the words")
+	sortFrequency := flag.Bool("sort-frequency", false, "order the words from the most common instead of the most frequent in the documents")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <document>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	hansWehr, err := clients.NewHansWehrClient(*db)
+	failIf(err)
+	defer hansWehr.Close()
+
+	// Any known language can be used from the command line
+	failIf(model.SetLanguages([]string{*lang}))
+	language := model.Languages()[0]
+
+	llm, err := clients.LLMFromEnv("SAHIB_LLM")
+	failIf(err)
+
+	opts := glossary.Options{
+		Top: *top,
+		Config: clients.SourceConfig{
+			PerplexityApiKey: os.Getenv("PERPLEXITY_API_KEY"),
+			LLM:              llm,
+		},
+		Lang:            language,
+		Vocabulary:      map[string]bool{},
+		SortByFrequency: *sortFrequency,
+	}
+	if *frequencyPath != "" {
+		opts.Ranks, err = frequency.Load(*frequencyPath)
+		failIf(err)
+	}
+	if *sources != "" {
+		opts.Sources = strings.Split(*sources, ",")
+	}
+
+	if *vocabularyPath != "" {
+		f, err := os.Open(*vocabularyPath)
+		failIf(err)
+		opts.Vocabulary, err = glossary.ParseVocabulary(f)
+		f.Close()
+		failIf(err)
+	}
+
+	texts := []string{}
+	for _, path := range flag.Args() {
+		content, err := os.ReadFile(path)
+		failIf(err)
+		text, err := glossary.ExtractText(path, content)
+		failIf(err)
+		texts = append(texts, text)
+	}
+
+	entries, err := glossary.Build(strings.Join(texts, "\n"), hansWehr, opts)
+	failIf(err)
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		failIf(err)
+		defer out.Close()
+	}
+
+	if *format == glossary.FormatHTML {
+		failIf(components.Glossary(entries).Render(context.Background(), out))
+		return
+	}
+	failIf(glossary.Write(out, *format, entries))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sahib/clients"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Imports a classical dictionary: the TEI files of Lane's Lexicon (.xml), in
+// the order of the dictionary, or tab separated files (.tsv) of root, word,
+// volume, page and definition.
+func main() {
+	db := flag.String("db", "assets/lane.sqlite", "path to the sqlite database to create")
+	volume := flag.Int("volume", 0, "volume of the xml files whose page breaks don't have one")
+	appendFiles := flag.Bool("append", false, "add the files to the imported ones instead of recreating the database")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <file.xml|file.tsv>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	files := []clients.LaneFile{}
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		failIf(err)
+		defer f.Close()
+		files = append(files, clients.LaneFile{Name: path, Reader: f})
+	}
+
+	conn, err := sql.Open("sqlite3", *db)
+	failIf(err)
+	defer conn.Close()
+
+	total, err := clients.ImportLane(conn, files, *volume, *appendFiles)
+	failIf(err)
+
+	log.Printf("Imported %d entries into %s", total, *db)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sahib/clients"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Imports the uthmani text of the Quran from https://tanzil.net/download/
+// (text with aya numbers) and the morphology of the Quranic Arabic Corpus
+// from https://corpus.quran.com/download/
+func main() {
+	db := flag.String("db", "assets/quran.sqlite", "path to the sqlite database to create")
+	text := flag.String("text", "quran-uthmani.txt", "text of the quran, in the sura|aya|text format")
+	morphology := flag.String("morphology", "quranic-corpus-morphology-0.4.txt", "morphology of the quranic arabic corpus")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	t, err := os.Open(*text)
+	failIf(err)
+	defer t.Close()
+
+	m, err := os.Open(*morphology)
+	failIf(err)
+	defer m.Close()
+
+	conn, err := sql.Open("sqlite3", *db)
+	failIf(err)
+	defer conn.Close()
+
+	verses, words, err := clients.ImportQuran(conn, t, m)
+	failIf(err)
+	log.Printf("Imported %d verses and %d words into %s", verses, words, *db)
+}
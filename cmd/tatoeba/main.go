@@ -0,0 +1,77 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sahib/clients"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// open opens an export of tatoeba, either plain, bzipped or in a bzipped tar.
+func open(path string) (io.Reader, io.Closer) {
+	f, err := os.Open(path)
+	failIf(err)
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".bz2") {
+		r = bzip2.NewReader(f)
+	}
+	if strings.HasSuffix(path, ".tar.bz2") {
+		archive := tar.NewReader(r)
+		_, err := archive.Next()
+		failIf(err)
+		r = archive
+	}
+	return r, f
+}
+
+// Imports the arabic sentences of tatoeba and their translations, from the
+// exports of https://tatoeba.org/en/downloads: either the whole sentences
+// (sentences.tar.bz2) or the ones of some languages (per_language/ara/ara_sentences.tsv.bz2...),
+// and the links between them (links.tar.bz2).
+func main() {
+	db := flag.String("db", "assets/tatoeba.sqlite", "path to the sqlite database to create")
+	links := flag.String("links", "links.tar.bz2", "export of the links between the sentences")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <sentences export>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	sentences := []io.Reader{}
+	for _, path := range flag.Args() {
+		r, c := open(path)
+		defer c.Close()
+		sentences = append(sentences, r)
+	}
+
+	l, c := open(*links)
+	defer c.Close()
+
+	conn, err := sql.Open("sqlite3", *db)
+	failIf(err)
+	defer conn.Close()
+
+	arabic, translations, err := clients.ImportTatoeba(conn, sentences, l)
+	failIf(err)
+	log.Printf("Imported %d arabic sentences and %d translations into %s", arabic, translations, *db)
+}
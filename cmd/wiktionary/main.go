@@ -0,0 +1,57 @@
+package main
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sahib/clients"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func failIf(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Imports a kaikki.org JSONL extract of the wiktionary (optionally gzipped),
+// e.g. https://kaikki.org/dictionary/Arabic/kaikki.org-dictionary-Arabic.jsonl
+func main() {
+	db := flag.String("db", "assets/wiktionary.sqlite", "path to the sqlite database to create")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <extract.jsonl[.gz]>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	failIf(err)
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(flag.Arg(0), ".gz") {
+		gz, err := gzip.NewReader(f)
+		failIf(err)
+		defer gz.Close()
+		r = gz
+	}
+
+	conn, err := sql.Open("sqlite3", *db)
+	failIf(err)
+	defer conn.Close()
+
+	count, err := clients.ImportWiktionary(conn, r)
+	failIf(err)
+	log.Printf("Imported %d entries into %s", count, *db)
+}
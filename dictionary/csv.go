@@ -0,0 +1,44 @@
+package dictionary
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadCSV reads a glossary with the headwords in the first column and their
+// definition in the second one, a first line with a "headword" or "word"
+// column is skipped.
+func ReadCSV(r io.Reader, comma rune) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	entries := []Entry{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid glossary at line %d: %w", line, err)
+		}
+		if len(record) < 2 {
+			continue
+		}
+
+		headword := strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		if line == 1 && (strings.EqualFold(headword, "headword") || strings.EqualFold(headword, "word")) {
+			continue
+		}
+		if headword == "" {
+			continue
+		}
+
+		entries = append(entries, Entry{Headword: headword, Definition: strings.TrimSpace(record[1])})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,197 @@
+// Package dictionary stores any number of local dictionaries (imported from
+// StarDict, XDXF or CSV files) in a common sqlite schema, each one being
+// queried as its own source.
+package dictionary
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Entry is a headword of a dictionary with its definition, which can be HTML.
+type Entry struct {
+	Headword   string
+	Definition string
+}
+
+type Dictionary struct {
+	ID      int64
+	Name    string
+	Format  string
+	Entries int
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+// The names are used as source names, so as ids and form fields in the UI.
+var validName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open dictionaries: %w", err)
+	}
+
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS dictionaries (
+            id INTEGER PRIMARY KEY,
+            name TEXT NOT NULL UNIQUE,
+            format TEXT NOT NULL,
+            imported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE TABLE IF NOT EXISTS entries (
+            id INTEGER PRIMARY KEY,
+            dictionary_id INTEGER NOT NULL REFERENCES dictionaries(id) ON DELETE CASCADE,
+            headword TEXT NOT NULL,
+            normalized TEXT NOT NULL,
+            definition TEXT NOT NULL
+        )`,
+		`CREATE INDEX IF NOT EXISTS entries_normalized ON entries(dictionary_id, normalized)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create the dictionaries schema: %w", err)
+		}
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func normalize(word string) string {
+	return clients.NormalizeAlef(clients.Normalize(strings.TrimSpace(word)))
+}
+
+func (s *Store) Dictionaries() ([]Dictionary, error) {
+	rows, err := s.db.Query(`SELECT d.id, d.name, d.format, COUNT(e.id) FROM dictionaries d
+        LEFT JOIN entries e ON e.dictionary_id = d.id GROUP BY d.id ORDER BY d.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dictionaries: %w", err)
+	}
+	defer rows.Close()
+
+	dicts := []Dictionary{}
+	for rows.Next() {
+		d := Dictionary{}
+		if err := rows.Scan(&d.ID, &d.Name, &d.Format, &d.Entries); err != nil {
+			return nil, fmt.Errorf("error while scanning dictionary: %w", err)
+		}
+		dicts = append(dicts, d)
+	}
+
+	return dicts, rows.Err()
+}
+
+// Import replaces the entries of the dictionary with the given name.
+func (s *Store) Import(name string, format string, entries []Entry) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid dictionary name %q, only letters, digits, - and _ are allowed", name)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM entries WHERE dictionary_id IN (SELECT id FROM dictionaries WHERE name = ?)`, name); err != nil {
+		return fmt.Errorf("failed to remove the previous entries of %s: %w", name, err)
+	}
+
+	var id int64
+	err = tx.QueryRow(`INSERT INTO dictionaries (name, format) VALUES (?, ?)
+        ON CONFLICT(name) DO UPDATE SET format = excluded.format, imported_at = CURRENT_TIMESTAMP
+        RETURNING id`, name, format).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create dictionary %s: %w", name, err)
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO entries (dictionary_id, headword, normalized, definition) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, e := range entries {
+		if _, err := insert.Exec(id, e.Headword, normalize(e.Headword), e.Definition); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", e.Headword, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) Delete(name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM entries WHERE dictionary_id IN (SELECT id FROM dictionaries WHERE name = ?)`, name); err != nil {
+		return fmt.Errorf("failed to delete the entries of %s: %w", name, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM dictionaries WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete dictionary %s: %w", name, err)
+	}
+
+	return tx.Commit()
+}
+
+// plainText removes the markup of the HTML definitions.
+func plainText(definition string) string {
+	if !strings.Contains(definition, "<") {
+		return definition
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(definition))
+	if err != nil {
+		return definition
+	}
+	doc.Find("br").ReplaceWithHtml("\n")
+	return strings.TrimSpace(doc.Text())
+}
+
+// Source returns the query function of a dictionary, the words are matched
+// without their diacritics.
+func (s *Store) Source(name string) clients.QueryFunc {
+	return func(word string, ignored model.Language) (*model.Translations, error) {
+		result := &model.Translations{}
+		start := time.Now()
+		defer func() {
+			result.Elapsed = clients.Elapsed(start)
+		}()
+
+		rows, err := s.db.Query(`SELECT e.headword, e.definition FROM entries e
+            JOIN dictionaries d ON d.id = e.dictionary_id
+            WHERE d.name = ? AND e.normalized = ? ORDER BY e.id LIMIT 50`, name, normalize(word))
+		if err != nil {
+			return result, fmt.Errorf("failed to query %s: %w", name, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var headword, definition string
+			if err := rows.Scan(&headword, &definition); err != nil {
+				return result, fmt.Errorf("error while scanning %s entry: %w", name, err)
+			}
+			result.List = append(result.List, model.Translation{Arabic: headword, Translation: plainText(definition)})
+		}
+
+		return result, rows.Err()
+	}
+}
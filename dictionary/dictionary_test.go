@@ -0,0 +1,232 @@
+package dictionary
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+// writeStarDict writes the .ifo, .idx and .dict (gzipped if asked) files of
+// a dictionary whose definitions are already encoded.
+func writeStarDict(t *testing.T, ifo string, words []string, definitions [][]byte, gzipped bool) string {
+	t.Helper()
+
+	base := filepath.Join(t.TempDir(), "test")
+	var idx, dict bytes.Buffer
+	for i, word := range words {
+		idx.WriteString(word)
+		idx.WriteByte(0)
+		binary.Write(&idx, binary.BigEndian, uint32(dict.Len()))
+		binary.Write(&idx, binary.BigEndian, uint32(len(definitions[i])))
+		dict.Write(definitions[i])
+	}
+
+	files := map[string][]byte{".ifo": []byte(ifo), ".idx": idx.Bytes(), ".dict": dict.Bytes()}
+	if gzipped {
+		var gz bytes.Buffer
+		w := gzip.NewWriter(&gz)
+		w.Write(dict.Bytes())
+		w.Close()
+		delete(files, ".dict")
+		files[".dict.dz"] = gz.Bytes()
+	}
+	for ext, content := range files {
+		if err := os.WriteFile(base+ext, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base + ".ifo"
+}
+
+// sized is a field of an upper case type, prefixed with its size.
+func sized(data string) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	return append(out, data...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestReadStarDict(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifo         string
+		definitions [][]byte
+		gzipped     bool
+		want        []string
+	}{
+		{
+			name:        "same type sequence",
+			ifo:         "StarDict's dict ifo file\nversion=2.4.2\nwordcount=2\nsametypesequence=m\n",
+			definitions: [][]byte{[]byte("book"), []byte(" to write ")},
+			want:        []string{"book", "to write"},
+		},
+		{
+			name:        "types in the data",
+			ifo:         "wordcount=2\n",
+			definitions: [][]byte{[]byte("mbook\x00"), []byte("hto <b>write</b>\x00gignored\x00")},
+			want:        []string{"book", "to <b>write</b>"},
+		},
+		{
+			name:        "upper case types are skipped",
+			ifo:         "wordcount=2\n",
+			definitions: [][]byte{concat([]byte("W"), sized("RIFF"), []byte("mbook\x00")), concat([]byte("P"), sized("\x89PNG"))},
+			want:        []string{"book", ""},
+		},
+		{
+			name:        "upper case types in the sequence",
+			ifo:         "wordcount=2\nsametypesequence=Wm\n",
+			definitions: [][]byte{concat(sized("RIFF"), []byte("book")), concat(sized(""), []byte("to write"))},
+			want:        []string{"book", "to write"},
+		},
+		{
+			name:        "dictzip",
+			ifo:         "wordcount=2\nsametypesequence=m\n",
+			definitions: [][]byte{[]byte("book"), []byte("to write")},
+			gzipped:     true,
+			want:        []string{"book", "to write"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := []string{"كتاب", "كتب"}
+			entries, err := ReadStarDict(writeStarDict(t, test.ifo, words, test.definitions, test.gzipped))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []Entry{}
+			for i, word := range words {
+				want = append(want, Entry{Headword: word, Definition: test.want[i]})
+			}
+			if !reflect.DeepEqual(entries, want) {
+				t.Errorf("ReadStarDict() = %+v, want %+v", entries, want)
+			}
+		})
+	}
+}
+
+func TestReadStarDictInvalid(t *testing.T) {
+	path := writeStarDict(t, "not a dictionary\n", nil, nil, false)
+	if _, err := ReadStarDict(path); err == nil {
+		t.Error("expected an error without wordcount")
+	}
+}
+
+func TestReadXDXF(t *testing.T) {
+	xdxf := `<?xml version="1.0" encoding="UTF-8"?>
+<xdxf lang_from="ARA" lang_to="FRE" format="visual">
+<full_name>Test</full_name>
+<ar><k>كتاب</k>
+livre&nbsp;; ouvrage</ar>
+<ar><k>كتب</k><k>كَتَبَ</k> <b>écrire</b></ar>
+<ar><k> </k>no key</ar>
+</xdxf>`
+
+	entries, err := ReadXDXF(strings.NewReader(xdxf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Headword: "كتاب", Definition: "livre ; ouvrage"},
+		{Headword: "كتب", Definition: "écrire"},
+		{Headword: "كَتَبَ", Definition: "écrire"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ReadXDXF() = %+v, want %+v", entries, want)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		comma rune
+		want  []Entry
+	}{
+		{
+			name:  "header and BOM",
+			input: "\ufeffheadword,definition\nكتاب,book\n",
+			comma: ',',
+			want:  []Entry{{Headword: "كتاب", Definition: "book"}},
+		},
+		{
+			name:  "tabs, quotes and short lines",
+			input: "كتاب\t\"a \"\"book\"\"\"\nalone\n\t\nكتب\t to write \textra\n",
+			comma: '\t',
+			want:  []Entry{{Headword: "كتاب", Definition: `a "book"`}, {Headword: "كتب", Definition: "to write"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := ReadCSV(strings.NewReader(test.input), test.comma)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.want) {
+				t.Errorf("ReadCSV() = %+v, want %+v", entries, test.want)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "dictionaries.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Import("bad name", "csv", nil); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+
+	if err := s.Import("Test", "csv", []Entry{{Headword: "كتاب", Definition: "old"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Importing again replaces the entries
+	entries := []Entry{{Headword: "كِتَاب", Definition: "book<br>volume"}, {Headword: "أمن", Definition: "safety"}}
+	if err := s.Import("Test", "stardict", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	dicts, err := s.Dictionaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dicts) != 1 || dicts[0].Name != "Test" || dicts[0].Format != "stardict" || dicts[0].Entries != 2 {
+		t.Errorf("Dictionaries() = %+v", dicts)
+	}
+
+	query := s.Source("Test")
+	for word, want := range map[string][]model.Translation{
+		"كتاب": {{Arabic: "كِتَاب", Translation: "book\nvolume"}},
+		"امن":  {{Arabic: "أمن", Translation: "safety"}},
+		"قلم":  nil,
+	} {
+		res, err := query(word, model.Language{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.List, want) {
+			t.Errorf("query(%q) = %+v, want %+v", word, res.List, want)
+		}
+	}
+
+	if err := s.Delete("Test"); err != nil {
+		t.Fatal(err)
+	}
+	if dicts, _ := s.Dictionaries(); len(dicts) != 0 {
+		t.Errorf("Dictionaries() = %+v after Delete", dicts)
+	}
+}
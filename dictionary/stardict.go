@@ -0,0 +1,153 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadStarDict reads the entries of a StarDict dictionary from its .ifo file,
+// the .idx (or .idx.gz) and .dict (or .dict.dz) files being next to it.
+func ReadStarDict(ifoPath string) ([]Entry, error) {
+	ifo, err := readIfo(ifoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(ifoPath, ".ifo")
+	idx, err := readMaybeGzipped(base+".idx", base+".idx.gz")
+	if err != nil {
+		return nil, err
+	}
+	dict, err := readMaybeGzipped(base+".dict", base+".dict.dz")
+	if err != nil {
+		return nil, err
+	}
+
+	offsetSize := 4
+	if ifo["idxoffsetbits"] == "64" {
+		offsetSize = 8
+	}
+	types := ifo["sametypesequence"]
+
+	entries := []Entry{}
+	for len(idx) > 0 {
+		end := bytes.IndexByte(idx, 0)
+		if end == -1 || len(idx) < end+1+offsetSize+4 {
+			return nil, fmt.Errorf("truncated stardict index")
+		}
+		word := string(idx[:end])
+		idx = idx[end+1:]
+
+		var offset uint64
+		if offsetSize == 8 {
+			offset = binary.BigEndian.Uint64(idx)
+		} else {
+			offset = uint64(binary.BigEndian.Uint32(idx))
+		}
+		size := uint64(binary.BigEndian.Uint32(idx[offsetSize:]))
+		idx = idx[offsetSize+4:]
+
+		if offset+size > uint64(len(dict)) {
+			return nil, fmt.Errorf("definition of %s is out of the dict file", word)
+		}
+
+		entries = append(entries, Entry{Headword: word, Definition: starDictData(dict[offset:offset+size], types)})
+	}
+
+	return entries, nil
+}
+
+// starDictData returns the text of the first textual field of a definition.
+// The fields of the lower case types (text, HTML...) end with a NUL byte, the
+// ones of the upper case types (pictures, sounds...) start with their size on
+// 4 bytes. With sametypesequence the types aren't in the data and the last
+// field has neither.
+func starDictData(data []byte, types string) string {
+	sequence := types != ""
+	for len(data) > 0 {
+		var typ byte
+		if sequence {
+			if types == "" {
+				break
+			}
+			typ, types = types[0], types[1:]
+		} else {
+			typ, data = data[0], data[1:]
+		}
+		last := sequence && types == ""
+
+		if typ >= 'A' && typ <= 'Z' {
+			if last || len(data) < 4 {
+				break
+			}
+			size := uint64(binary.BigEndian.Uint32(data))
+			if size > uint64(len(data)-4) {
+				break
+			}
+			data = data[4+size:]
+			continue
+		}
+
+		field := data
+		if end := bytes.IndexByte(data, 0); end != -1 && !last {
+			field = data[:end]
+		}
+		return strings.TrimSpace(string(field))
+	}
+
+	return ""
+}
+
+func readIfo(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ifo := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			ifo[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := strconv.Atoi(ifo["wordcount"]); err != nil {
+		return nil, fmt.Errorf("%s is not a stardict .ifo file", path)
+	}
+
+	return ifo, nil
+}
+
+// readMaybeGzipped reads the plain file if it exists and the gzipped one
+// otherwise (dictzip files are valid gzip files).
+func readMaybeGzipped(plain string, gzipped string) ([]byte, error) {
+	if content, err := os.ReadFile(plain); err == nil {
+		return content, nil
+	}
+
+	f, err := os.Open(gzipped)
+	if err != nil {
+		return nil, fmt.Errorf("neither %s nor %s can be read: %w", plain, gzipped, err)
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", gzipped, err)
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
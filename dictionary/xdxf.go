@@ -0,0 +1,85 @@
+package dictionary
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadXDXF reads the articles of an XDXF dictionary, an article (<ar>) has
+// one or more keys (<k>) followed by its definition.
+func ReadXDXF(r io.Reader) ([]Entry, error) {
+	decoder := xml.NewDecoder(r)
+	// Some dictionaries use HTML entities
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	entries := []Entry{}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xdxf: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "ar" {
+			continue
+		}
+
+		article, err := readArticle(decoder)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, article...)
+	}
+
+	return entries, nil
+}
+
+// readArticle reads an <ar> element until its end, it returns an entry per key.
+func readArticle(decoder *xml.Decoder) ([]Entry, error) {
+	keys := []string{}
+	var key, definition strings.Builder
+	inKey, depth := false, 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid xdxf article: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Local == "k" {
+				inKey = true
+				key.Reset()
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				// End of the article
+				entries := make([]Entry, 0, len(keys))
+				for _, k := range keys {
+					entries = append(entries, Entry{Headword: k, Definition: strings.TrimSpace(definition.String())})
+				}
+				return entries, nil
+			}
+			depth--
+			if t.Name.Local == "k" {
+				inKey = false
+				if k := strings.TrimSpace(key.String()); k != "" {
+					keys = append(keys, k)
+				}
+			}
+		case xml.CharData:
+			if inKey {
+				key.Write(t)
+			} else {
+				definition.Write(t)
+			}
+		}
+	}
+}
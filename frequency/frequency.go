@@ -0,0 +1,261 @@
+// Package frequency ranks the words by how common they are, from an imported
+// frequency list or from the words counted in a corpus.
+package frequency
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"sahib/clients"
+	"sahib/model"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Highest rank of each band.
+var bands = []struct {
+	maxRank int
+	band    string
+}{
+	{1000, model.BandVeryCommon},
+	{3000, model.BandCommon},
+	{10000, model.BandUncommon},
+}
+
+// Band returns the band of a rank.
+func Band(rank int) string {
+	if rank <= 0 {
+		return ""
+	}
+	for _, b := range bands {
+		if rank <= b.maxRank {
+			return b.band
+		}
+	}
+	return model.BandRare
+}
+
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+func key(word string) string {
+	return clients.NormalizeAlef(clients.Normalize(strings.TrimSpace(word)))
+}
+
+// Count adds the arabic words of the text to the counts.
+func Count(counts map[string]int, text string) {
+	for _, tok := range clients.Tokenize(text) {
+		if tok.Word {
+			counts[key(tok.Text)]++
+		}
+	}
+}
+
+// Sorted returns the counts, most frequent first.
+func Sorted(counts map[string]int) []WordCount {
+	out := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		out = append(out, WordCount{Word: w, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Word < out[j].Word
+	})
+	return out
+}
+
+// ReadList reads a frequency list with a word per line, most frequent first,
+// optionally followed by its count (separated by spaces or a tab).
+func ReadList(r io.Reader) ([]WordCount, error) {
+	counts := map[string]int{}
+	order := []string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		word := key(fields[0])
+		count := 0
+		if len(fields) > 1 {
+			c, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid count at line %d: %w", line, err)
+			}
+			count = c
+		}
+
+		// The normalized forms of several words are merged
+		if _, ok := counts[word]; !ok {
+			order = append(order, word)
+		}
+		counts[word] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]WordCount, len(order))
+	for i, w := range order {
+		out[i] = WordCount{Word: w, Count: counts[w]}
+	}
+	// Keep the order of the list for the words without count
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Count > out[j].Count
+	})
+	return out, nil
+}
+
+// Import (re)creates the frequency table of the database with the words,
+// ranked in their order.
+func Import(db *sql.DB, words []WordCount) error {
+	schema := []string{
+		`DROP TABLE IF EXISTS frequencies`,
+		`CREATE TABLE frequencies (
+            word TEXT PRIMARY KEY,
+            count INTEGER NOT NULL,
+            rank INTEGER NOT NULL
+        )`,
+	}
+
+	// The previous list is kept if the import fails
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range schema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create the frequency table: %w", err)
+		}
+	}
+
+	insert, err := tx.Prepare(`INSERT OR IGNORE INTO frequencies (word, count, rank) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for i, w := range words {
+		if w.Word == "" {
+			continue
+		}
+		if _, err := insert.Exec(w.Word, w.Count, i+1); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", w.Word, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Ranks are the ranks of the imported words, kept in memory to annotate the
+// results.
+type Ranks struct {
+	ranks map[string]int
+}
+
+// Load reads the ranks imported in the database.
+func Load(path string) (*Ranks, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open the frequency list: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT word, rank FROM frequencies`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the frequency list: %w", err)
+	}
+	defer rows.Close()
+
+	r := &Ranks{ranks: map[string]int{}}
+	for rows.Next() {
+		var word string
+		var rank int
+		if err := rows.Scan(&word, &rank); err != nil {
+			return nil, fmt.Errorf("error while scanning frequency: %w", err)
+		}
+		r.ranks[word] = rank
+	}
+
+	return r, rows.Err()
+}
+
+// Of returns the frequency of a word, or of its stem without clitics if the
+// word itself isn't in the list. Only single words are ranked.
+func (r *Ranks) Of(word string) model.Frequency {
+	if r == nil || strings.ContainsAny(strings.TrimSpace(word), " \t\n") {
+		return model.Frequency{}
+	}
+
+	for _, candidate := range clients.Candidates(word) {
+		if rank, ok := r.ranks[clients.NormalizeAlef(candidate)]; ok {
+			return model.Frequency{Rank: rank, Band: Band(rank)}
+		}
+	}
+	return model.Frequency{}
+}
+
+// Annotate sets the frequency of the result rows and definitions.
+func (r *Ranks) Annotate(all []model.TranslationsAndSource, defs *model.Definitions) {
+	if r == nil {
+		return
+	}
+
+	for _, ts := range all {
+		for i, row := range ts.Translations.List {
+			ts.Translations.List[i].Frequency = r.Of(row.Arabic)
+		}
+	}
+
+	if defs == nil {
+		return
+	}
+	for i, def := range defs.Definitions {
+		defs.Definitions[i].Frequency = r.Of(def.Word)
+	}
+}
+
+// less orders the known words by rank, before the unknown ones.
+func less(a model.Frequency, b model.Frequency) bool {
+	if a.Known() != b.Known() {
+		return a.Known()
+	}
+	return a.Rank < b.Rank
+}
+
+// Sort orders the rows of every result and the definitions from the most to
+// the least common word, the words missing from the list last.
+func Sort(all []model.TranslationsAndSource, defs *model.Definitions) {
+	for _, ts := range all {
+		rows := ts.Translations.List
+		sort.SliceStable(rows, func(i, j int) bool {
+			return less(rows[i].Frequency, rows[j].Frequency)
+		})
+	}
+
+	if defs == nil {
+		return
+	}
+	sort.SliceStable(defs.Definitions, func(i, j int) bool {
+		return less(defs.Definitions[i].Frequency, defs.Definitions[j].Frequency)
+	})
+}
+
+// SortGlossary orders the glossary entries from the most to the least common
+// word.
+func SortGlossary(entries []model.GlossaryEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i].Frequency, entries[j].Frequency)
+	})
+}
@@ -0,0 +1,175 @@
+package frequency
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+func TestBand(t *testing.T) {
+	tests := []struct {
+		rank int
+		want string
+	}{
+		{0, ""},
+		{-1, ""},
+		{1, model.BandVeryCommon},
+		{1000, model.BandVeryCommon},
+		{1001, model.BandCommon},
+		{3000, model.BandCommon},
+		{3001, model.BandUncommon},
+		{10000, model.BandUncommon},
+		{10001, model.BandRare},
+	}
+
+	for _, test := range tests {
+		if got := Band(test.rank); got != test.want {
+			t.Errorf("Band(%d) = %q, want %q", test.rank, got, test.want)
+		}
+	}
+}
+
+func TestReadList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []WordCount
+	}{
+		{
+			name:  "without counts",
+			input: "في\nمن\n\n# comment\nعلى\n",
+			want:  []WordCount{{"في", 0}, {"من", 0}, {"على", 0}},
+		},
+		{
+			name:  "counts reorder and merge the normalized words",
+			input: "من 50\nفِي\t80\nأمن 3\nامن 4\n",
+			want:  []WordCount{{"في", 80}, {"من", 50}, {"امن", 7}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ReadList(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ReadList() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := ReadList(strings.NewReader("في كثير\n")); err == nil {
+		t.Error("expected an error for an invalid count")
+	}
+}
+
+func TestCountAndSorted(t *testing.T) {
+	counts := map[string]int{}
+	Count(counts, "ذهب الولدُ إلى المدرسة، وذهب أخوه إلى السوق. Hello")
+	want := []WordCount{{"الى", 2}, {"اخوه", 1}, {"السوق", 1}, {"المدرسة", 1}, {"الولد", 1}, {"ذهب", 1}, {"وذهب", 1}}
+	if got := Sorted(counts); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sorted() = %v, want %v", got, want)
+	}
+}
+
+// importTest imports the words in a temporary database and loads their ranks.
+func importTest(t *testing.T, words []WordCount) (*sql.DB, *Ranks) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "frequency.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := Import(db, words); err != nil {
+		t.Fatal(err)
+	}
+	ranks, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, ranks
+}
+
+func TestRanksOf(t *testing.T) {
+	_, ranks := importTest(t, []WordCount{{"في", 80}, {"", 60}, {"كتاب", 50}, {"مدرسة", 3}})
+
+	tests := []struct {
+		word string
+		want model.Frequency
+	}{
+		{"في", model.Frequency{Rank: 1, Band: model.BandVeryCommon}},
+		// The empty words keep their rank
+		{"كِتَاب", model.Frequency{Rank: 3, Band: model.BandVeryCommon}},
+		{"والكتاب", model.Frequency{Rank: 3, Band: model.BandVeryCommon}},
+		{"مدرستها", model.Frequency{Rank: 4, Band: model.BandVeryCommon}},
+		{"قلم", model.Frequency{}},
+		{"في كتاب", model.Frequency{}},
+	}
+
+	for _, test := range tests {
+		if got := ranks.Of(test.word); got != test.want {
+			t.Errorf("Of(%q) = %+v, want %+v", test.word, got, test.want)
+		}
+	}
+
+	var none *Ranks
+	if got := none.Of("في"); got.Known() {
+		t.Errorf("Of() without list = %+v", got)
+	}
+}
+
+func TestImportDuplicates(t *testing.T) {
+	db, _ := importTest(t, []WordCount{{"في", 80}, {"من", 50}})
+
+	// A list merged from several corpora, imported over the previous one
+	if err := Import(db, []WordCount{{"على", 3}, {"من", 2}, {"على", 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT word, count, rank FROM frequencies ORDER BY rank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var word string
+		var count, rank int
+		rows.Scan(&word, &count, &rank)
+		got = append(got, fmt.Sprintf("%s %d #%d", word, count, rank))
+	}
+	// The duplicates keep their first count and rank
+	if want := []string{"على 3 #1", "من 2 #2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frequencies = %q, want %q", got, want)
+	}
+}
+
+func TestSort(t *testing.T) {
+	all := []model.TranslationsAndSource{{Source: "test", Translations: &model.Translations{List: []model.Translation{
+		{Arabic: "قلم"},
+		{Arabic: "كتاب", Frequency: model.Frequency{Rank: 30}},
+		{Arabic: "في", Frequency: model.Frequency{Rank: 1}},
+	}}}}
+	defs := &model.Definitions{Definitions: []model.Definition{{Word: "a"}, {Word: "b", Frequency: model.Frequency{Rank: 2}}}}
+
+	Sort(all, defs)
+
+	var rows []string
+	for _, row := range all[0].Translations.List {
+		rows = append(rows, row.Arabic)
+	}
+	if want := []string{"في", "كتاب", "قلم"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("Sort() = %v, want %v", rows, want)
+	}
+	if defs.Definitions[0].Word != "b" {
+		t.Errorf("Sort() definitions = %+v", defs.Definitions)
+	}
+}
@@ -0,0 +1,175 @@
+package glossary
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ExtractText returns the text content of a document, the format is guessed
+// from the file name extension: .txt, .srt, .html and .epub are supported.
+func ExtractText(name string, content []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt", "":
+		return string(content), nil
+	case ".srt":
+		return extractSRT(content), nil
+	case ".html", ".htm", ".xhtml":
+		return extractHTML(bytes.NewReader(content))
+	case ".epub":
+		return extractEPUB(content)
+	default:
+		return "", fmt.Errorf("unsupported file format: %s", name)
+	}
+}
+
+var srtTags = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+
+func extractSRT(content []byte) string {
+	var out strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip the cue numbers and the timestamps
+		if line == "" || strings.Contains(line, "-->") || strings.Trim(line, "0123456789\ufeff") == "" {
+			continue
+		}
+		out.WriteString(srtTags.ReplaceAllString(line, ""))
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
+
+func extractHTML(r io.Reader) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	doc.Find("script, style").Remove()
+	return doc.Text(), nil
+}
+
+func extractEPUB(content []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open epub: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	names, err := epubSpine(files)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, name := range names {
+		f, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s in epub: %w", f.Name, err)
+		}
+		text, err := extractHTML(r)
+		r.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to extract %s in epub: %w", f.Name, err)
+		}
+
+		out.WriteString(text)
+		out.WriteString("\n")
+	}
+
+	return out.String(), nil
+}
+
+// epubSpine returns the documents of the book in reading order, as listed by
+// the spine of its package (OPF) file. Without a package file, the html
+// documents are read in the order of their names.
+func epubSpine(files map[string]*zip.File) ([]string, error) {
+	container := struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}{}
+	if err := decodeXML(files["META-INF/container.xml"], &container); err != nil || len(container.Rootfiles) == 0 {
+		return htmlFiles(files), nil
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	opf := struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}{}
+	if err := decodeXML(files[opfPath], &opf); err != nil {
+		return nil, fmt.Errorf("failed to read the epub package %s: %w", opfPath, err)
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range opf.Items {
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), href)
+	}
+
+	names := []string{}
+	for _, ref := range opf.Spine {
+		if name, ok := hrefs[ref.IDRef]; ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func decodeXML(f *zip.File, v any) error {
+	if f == nil {
+		return fmt.Errorf("missing file")
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// htmlFiles returns the html documents of the archive sorted by name.
+func htmlFiles(files map[string]*zip.File) []string {
+	names := []string{}
+	for name := range files {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".html", ".htm", ".xhtml":
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
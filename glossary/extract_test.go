@@ -0,0 +1,101 @@
+package glossary
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExtractSRT(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\n<i>مرحبا</i>\n\n2\n00:00:03,000 --> 00:00:04,000\nكيف حالك؟\n"
+	text, err := ExtractText("movie.srt", []byte(srt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "مرحبا\nكيف حالك؟\n"; text != want {
+		t.Errorf("ExtractText(srt) = %q, want %q", text, want)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	text, err := ExtractText("page.html", []byte(`<html><head><style>p {}</style></head><body><p>كتاب</p><script>var a</script></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(text) != "كتاب" {
+		t.Errorf("ExtractText(html) = %q", text)
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if _, err := ExtractText("book.pdf", nil); err == nil {
+		t.Error("ExtractText(pdf) should fail")
+	}
+}
+
+func epub(t *testing.T, files map[string]string, order []string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range order {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractEPUBSpineOrder(t *testing.T) {
+	files := map[string]string{
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="c2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/chapter1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+  </manifest>
+  <spine><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/nav.xhtml":              `<html><body>فهرس</body></html>`,
+		"OEBPS/text/chapter 2.xhtml":   `<html><body>ثاني</body></html>`,
+		"OEBPS/text/chapter1.xhtml":    `<html><body>أول</body></html>`,
+		"OEBPS/text/not-in-spine.html": `<html><body>خارج</body></html>`,
+	}
+	// The second chapter comes first in the archive
+	content := epub(t, files, []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/text/chapter 2.xhtml", "OEBPS/text/chapter1.xhtml", "OEBPS/text/not-in-spine.html"})
+
+	text, err := ExtractText("book.epub", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(text); strings.Join(got, " ") != "أول ثاني" {
+		t.Errorf("ExtractText(epub) = %q, want the chapters of the spine in order", got)
+	}
+}
+
+func TestExtractEPUBWithoutPackage(t *testing.T) {
+	files := map[string]string{
+		"b.html": `<html><body>ثاني</body></html>`,
+		"a.html": `<html><body>أول</body></html>`,
+	}
+	text, err := ExtractText("book.epub", epub(t, files, []string{"b.html", "a.html"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(text); strings.Join(got, " ") != "أول ثاني" {
+		t.Errorf("ExtractText(epub) = %q", got)
+	}
+}
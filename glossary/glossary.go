@@ -0,0 +1,302 @@
+// Package glossary builds glossaries of the most frequent unknown words of a text.
+package glossary
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"sahib/clients"
+	"sahib/frequency"
+	"sahib/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatAnki = "anki"
+	FormatHTML = "html"
+)
+
+var Formats = []string{FormatCSV, FormatAnki, FormatHTML}
+
+// Maximum number of concurrent requests to the remote sources.
+const maxConcurrency = 4
+
+// Words too common to be worth having in a glossary.
+var stopWords = toSet(strings.Fields(`
+في من على إلى الى عن مع هذا هذه ذلك تلك التي الذي الذين هو هي هم هن أنا انا أنت انت نحن
+أن ان إن لا ما لم لن قد كان كانت ثم أو او بل كل بعض عند بين حتى إذا اذا لكن غير يا و ف ب ل ك
+`))
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[clients.Normalize(w)] = true
+	}
+	return set
+}
+
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// Frequencies counts the normalized arabic words of a text, most frequent first.
+func Frequencies(text string) []WordCount {
+	counts := map[string]int{}
+	for _, tok := range clients.Tokenize(text) {
+		if !tok.Word {
+			continue
+		}
+		word := clients.Normalize(tok.Text)
+		if len([]rune(word)) < 2 || stopWords[word] {
+			continue
+		}
+		counts[word]++
+	}
+
+	out := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		out = append(out, WordCount{Word: w, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Word < out[j].Word
+	})
+
+	return out
+}
+
+// ParseVocabulary reads a vocabulary with one word per line, optionally
+// followed by a tab and its translation (as copied from the reader page).
+func ParseVocabulary(r io.Reader) (map[string]bool, error) {
+	vocabulary := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word, _, _ := strings.Cut(scanner.Text(), "\t")
+		if word = clients.Normalize(word); word != "" {
+			vocabulary[word] = true
+		}
+	}
+
+	return vocabulary, scanner.Err()
+}
+
+type Options struct {
+	// Number of unknown words to put in the glossary
+	Top        int
+	Sources    []string
+	Config     clients.SourceConfig
+	Lang       model.Language
+	Vocabulary map[string]bool
+	// Frequencies of the words, can be nil
+	Ranks *frequency.Ranks
+	// Whether to order the entries from the most common word instead of the
+	// most frequent in the text
+	SortByFrequency bool
+}
+
+// Build creates the glossary of the top unknown words of a text using Hans
+// Wehr and the selected remote sources.
+func Build(text string, hansWehr *clients.HansWehr, opts Options) ([]model.GlossaryEntry, error) {
+	sources := make([]clients.QueryFunc, len(opts.Sources))
+	for i, name := range opts.Sources {
+		if !clients.Supports(name, opts.Lang) {
+			return nil, fmt.Errorf("%s doesn't support %s", name, opts.Lang.Name)
+		}
+		fn, err := opts.Config.Source(name)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = fn
+	}
+
+	counts := []WordCount{}
+	words := []string{}
+	for _, wc := range Frequencies(text) {
+		if !opts.Vocabulary[wc.Word] {
+			counts = append(counts, wc)
+			words = append(words, wc.Word)
+		}
+	}
+
+	lookups, err := hansWehr.LookupAll(words)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := merge(counts, lookups, opts.Vocabulary)
+	if len(entries) > opts.Top {
+		entries = entries[:opts.Top]
+	}
+	for i := range entries {
+		entries[i].Frequency = opts.Ranks.Of(entries[i].Word)
+	}
+
+	if opts.SortByFrequency {
+		frequency.SortGlossary(entries)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+	for i := range entries {
+		entries[i].Translations = make([]model.TranslationsAndSource, len(sources))
+		for j, fn := range sources {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				word := entries[i].Word
+				if entries[i].Match != "" {
+					word = entries[i].Match
+				}
+				res, err := fn(word, opts.Lang)
+				if err != nil {
+					log.Printf("Failed to query %s for %s: %s", opts.Sources[j], word, err)
+					res = &model.Translations{Error: err.Error()}
+				}
+				entries[i].Translations[j] = model.TranslationsAndSource{Translations: res, Source: opts.Sources[j]}
+			}()
+		}
+	}
+	wg.Wait()
+
+	return entries, nil
+}
+
+// merge groups the words resolving to the same Hans Wehr headword (e.g. كتاب,
+// الكتاب and كتابه) in a single entry named after its most frequent form, the
+// words whose headword is in the vocabulary are left out.
+func merge(counts []WordCount, lookups map[string]clients.LookupResult, vocabulary map[string]bool) []model.GlossaryEntry {
+	entries := []model.GlossaryEntry{}
+	byHeadword := map[string]int{}
+	for _, wc := range counts {
+		l := lookups[wc.Word]
+		if l.Definitions == nil || len(l.Definitions.Definitions) == 0 {
+			entries = append(entries, model.GlossaryEntry{Word: wc.Word, Count: wc.Count})
+			continue
+		}
+		headword := clients.Normalize(l.Definitions.Definitions[0].Word)
+		if vocabulary[l.Match] || vocabulary[headword] {
+			continue
+		}
+
+		if i, ok := byHeadword[headword]; ok {
+			entries[i].Count += wc.Count
+			continue
+		}
+		byHeadword[headword] = len(entries)
+		entries = append(entries, model.GlossaryEntry{
+			Word:       wc.Word,
+			Count:      wc.Count,
+			Match:      l.Match,
+			Definition: clients.Gloss(l.Definitions.Definitions[0].Definition, 300),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Count > entries[j].Count
+	})
+	return entries
+}
+
+// Maximum number of translations kept per source in the exports.
+const maxTranslations = 3
+
+func translations(ts model.TranslationsAndSource) string {
+	out := []string{}
+	for i, row := range ts.Translations.List {
+		if i == maxTranslations {
+			break
+		}
+		out = append(out, row.Translation)
+	}
+	return strings.Join(out, "; ")
+}
+
+// Write exports the glossary in the text formats, the html page is rendered
+// by the components.
+func Write(w io.Writer, format string, entries []model.GlossaryEntry) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, entries)
+	case FormatAnki:
+		return writeAnki(w, entries)
+	default:
+		return fmt.Errorf("unknown glossary format: %s", format)
+	}
+}
+
+func writeCSV(w io.Writer, entries []model.GlossaryEntry) error {
+	out := csv.NewWriter(w)
+	header := []string{"word", "count", "rank", "band", model.SourceWehr}
+	if len(entries) > 0 {
+		for _, ts := range entries[0].Translations {
+			header = append(header, ts.Source)
+		}
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		record := []string{e.Word, strconv.Itoa(e.Count), rank(e.Frequency), e.Frequency.Band, e.Definition}
+		for _, ts := range e.Translations {
+			record = append(record, translations(ts))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// rank is empty for the words missing from the frequency list.
+func rank(f model.Frequency) string {
+	if !f.Known() {
+		return ""
+	}
+	return strconv.Itoa(f.Rank)
+}
+
+// writeAnki writes a tab separated file that can be imported as notes in Anki,
+// the front being the word and the back all the translations.
+func writeAnki(w io.Writer, entries []model.GlossaryEntry) error {
+	if _, err := io.WriteString(w, "#separator:tab\n#html:true\n"); err != nil {
+		return err
+	}
+
+	clean := strings.NewReplacer("\t", " ", "\n", " ")
+	for _, e := range entries {
+		back := []string{}
+		if e.Frequency.Known() {
+			back = append(back, "<small>"+e.Frequency.Band+" (#"+rank(e.Frequency)+")</small>")
+		}
+		if e.Definition != "" {
+			back = append(back, html.EscapeString(e.Definition))
+		}
+		for _, ts := range e.Translations {
+			if t := translations(ts); t != "" {
+				back = append(back, "<b>"+ts.Source+"</b>: "+html.EscapeString(t))
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", clean.Replace(e.Word), clean.Replace(strings.Join(back, "<br>"))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package glossary
+
+import (
+	"bytes"
+	"reflect"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+func TestFrequencies(t *testing.T) {
+	got := Frequencies("الكتابُ في البيتِ. الكتاب جميل، والكتاب كتابي.")
+	want := []WordCount{
+		{"الكتاب", 2},
+		{"البيت", 1},
+		{"جميل", 1},
+		{"كتابي", 1},
+		{"والكتاب", 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Frequencies = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVocabulary(t *testing.T) {
+	vocabulary, err := ParseVocabulary(strings.NewReader("كِتَاب\tbook\nبيت\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"كتاب": true, "بيت": true}; !reflect.DeepEqual(vocabulary, want) {
+		t.Errorf("ParseVocabulary = %v, want %v", vocabulary, want)
+	}
+}
+
+func lookup(headword string, match string) clients.LookupResult {
+	return clients.LookupResult{
+		Match:       match,
+		Definitions: &model.Definitions{Definitions: []model.Definition{{Word: headword, Definition: "<b>" + headword + "</b> def"}}},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	counts := []WordCount{{"الكتاب", 3}, {"جميل", 2}, {"كتابي", 2}, {"قلم", 1}, {"بيت", 1}}
+	lookups := map[string]clients.LookupResult{
+		"الكتاب": lookup("كتاب", "كتاب"),
+		"كتابي":  lookup("كتاب", "كتاب"),
+		"جميل":   lookup("جميل", "جميل"),
+		"بيت":    lookup("بيت", "بيت"),
+		"قلم":    {Definitions: &model.Definitions{}},
+	}
+
+	got := merge(counts, lookups, map[string]bool{"بيت": true})
+	want := []model.GlossaryEntry{
+		{Word: "الكتاب", Count: 5, Match: "كتاب", Definition: "كتاب def"},
+		{Word: "جميل", Count: 2, Match: "جميل", Definition: "جميل def"},
+		{Word: "قلم", Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merge = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []model.GlossaryEntry{
+		{
+			Word:       "كتاب",
+			Count:      2,
+			Definition: "book",
+			Frequency:  model.Frequency{Rank: 120, Band: model.BandVeryCommon},
+			Translations: []model.TranslationsAndSource{{
+				Source:       model.SourceMaany,
+				Translations: &model.Translations{List: []model.Translation{{Translation: "livre"}, {Translation: "écrit"}}},
+			}},
+		},
+		{Word: "قلم", Count: 1, Translations: []model.TranslationsAndSource{{Source: model.SourceMaany, Translations: &model.Translations{}}}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, entries); err != nil {
+		t.Fatal(err)
+	}
+	want := "word,count,rank,band,HansWehr,Maany\nكتاب,2,120,very common,book,livre; écrit\nقلم,1,,,,\n"
+	if buf.String() != want {
+		t.Errorf("Write(csv) = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatHTML, nil); err == nil {
+		t.Error("the html glossary is rendered by the components, Write should fail")
+	}
+}
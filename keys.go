@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/store"
+	"strings"
+	"sync"
+)
+
+// Providers whose API key can be configured, see clients.LLMPresets.
+var keyProviders = []string{"perplexity", "openai"}
+
+// keyring holds the API keys of the LLM providers. A key is read from the
+// <PROVIDER>_API_KEY environment variable, else from the JSON file of
+// SAHIB_KEYS, else from the database where it is encrypted with SAHIB_SECRET.
+type keyring struct {
+	store *store.Store
+	file  map[string]string
+	// nil if SAHIB_SECRET isn't set, the keys can't be stored then
+	aead cipher.AEAD
+
+	mu    sync.Mutex
+	cache map[string]model.APIKeyStatus
+	keys  map[string]string
+}
+
+func newKeyring(st *store.Store) (*keyring, error) {
+	k := &keyring{store: st, file: map[string]string{}, cache: map[string]model.APIKeyStatus{}, keys: map[string]string{}}
+
+	if path := os.Getenv("SAHIB_KEYS"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys: %w", err)
+		}
+		if err := json.Unmarshal(content, &k.file); err != nil {
+			return nil, fmt.Errorf("invalid keys file %s: %w", path, err)
+		}
+	}
+
+	if secret := os.Getenv("SAHIB_SECRET"); secret != "" {
+		sum := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+		k.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+func keyHint(key string) string {
+	if len(key) <= 8 {
+		return "…"
+	}
+	return "…" + key[len(key)-4:]
+}
+
+// resolve returns the key of the provider and where it comes from, the
+// callers must hold the lock.
+func (k *keyring) resolve(provider string) (string, model.APIKeyStatus) {
+	if status, ok := k.cache[provider]; ok {
+		return k.keys[provider], status
+	}
+
+	key, origin := os.Getenv(strings.ToUpper(provider)+"_API_KEY"), model.KeyFromEnv
+	if key == "" {
+		key, origin = k.file[provider], model.KeyFromFile
+	}
+	if key == "" && k.aead != nil {
+		key, origin = k.open(provider), model.KeyFromStore
+	}
+
+	status := model.APIKeyStatus{Provider: provider}
+	if key != "" {
+		status.Origin = origin
+		status.Hint = keyHint(key)
+	}
+	k.cache[provider] = status
+	k.keys[provider] = key
+
+	return key, status
+}
+
+func (k *keyring) open(provider string) string {
+	sealed, err := k.store.APIKey(provider)
+	if err != nil || sealed == nil {
+		if err != nil {
+			log.Printf("Failed to read the %s API key: %s", provider, err)
+		}
+		return ""
+	}
+
+	key, err := k.aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(provider))
+	if err != nil {
+		log.Printf("Failed to decrypt the %s API key, was SAHIB_SECRET changed? %s", provider, err)
+		return ""
+	}
+
+	return string(key)
+}
+
+func (k *keyring) APIKey(provider string) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, _ := k.resolve(provider)
+	return key
+}
+
+func (k *keyring) statuses() []model.APIKeyStatus {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	out := make([]model.APIKeyStatus, 0, len(keyProviders))
+	for _, provider := range keyProviders {
+		_, status := k.resolve(provider)
+		out = append(out, status)
+	}
+	return out
+}
+
+func (k *keyring) canStore() bool {
+	return k.aead != nil
+}
+
+func (k *keyring) setAPIKey(provider string, key string) error {
+	if k.aead == nil {
+		return fmt.Errorf("SAHIB_SECRET must be set to store API keys")
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := store.SealedKey{Nonce: nonce, Ciphertext: k.aead.Seal(nil, nonce, []byte(key), []byte(provider))}
+	if err := k.store.SaveAPIKey(provider, sealed); err != nil {
+		return err
+	}
+
+	k.forget(provider)
+	return nil
+}
+
+func (k *keyring) deleteAPIKey(provider string) error {
+	if err := k.store.DeleteAPIKey(provider); err != nil {
+		return err
+	}
+
+	k.forget(provider)
+	return nil
+}
+
+func (k *keyring) forget(provider string) {
+	k.mu.Lock()
+	delete(k.cache, provider)
+	delete(k.keys, provider)
+	k.mu.Unlock()
+}
+
+// withKey returns the LLM client with the key of its preset if it was not
+// configured with one, nil stays nil. The custom endpoints have no preset
+// and keep their own key.
+func (k *keyring) withKey(llm *clients.LLMClient) *clients.LLMClient {
+	if llm == nil || llm.ApiKey != "" || llm.Preset == "" {
+		return llm
+	}
+
+	key := k.APIKey(llm.Preset)
+	if key == "" {
+		return llm
+	}
+
+	client := *llm
+	client.ApiKey = key
+	return &client
+}
+
+// sourceConfig returns the configuration of the remote sources with the
+// server keys.
+func (k *keyring) sourceConfig(llm *clients.LLMClient) clients.SourceConfig {
+	return clients.SourceConfig{PerplexityApiKey: k.APIKey("perplexity"), LLM: k.withKey(llm)}
+}
+
+func isKeyProvider(provider string) bool {
+	for _, p := range keyProviders {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+func registerKeyHandlers(auth *auth, keys *keyring) {
+	render := func(w http.ResponseWriter, r *http.Request, formErr string) {
+		component := components.AdminKeys(keys.statuses(), keys.canStore(), formErr, auth.csrfToken(adminName))
+		component.Render(r.Context(), w)
+	}
+
+	http.HandleFunc("GET /admin/keys", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		render(w, r, "")
+	}))
+
+	http.HandleFunc("POST /admin/keys", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		provider := r.FormValue("provider")
+		key := strings.TrimSpace(r.FormValue(model.ApiKey))
+		if !isKeyProvider(provider) || key == "" {
+			render(w, r, "A provider and a key are required")
+			return
+		}
+
+		if err := keys.setAPIKey(provider, key); err != nil {
+			render(w, r, err.Error())
+			return
+		}
+
+		log.Printf("Saved the %s API key", provider)
+		http.Redirect(w, r, "/admin/keys", http.StatusSeeOther)
+	}))
+
+	http.HandleFunc("POST /admin/keys/{provider}/delete", auth.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		if err := keys.deleteAPIKey(r.PathValue("provider")); err != nil {
+			render(w, r, err.Error())
+			return
+		}
+
+		http.Redirect(w, r, "/admin/keys", http.StatusSeeOther)
+	}))
+}
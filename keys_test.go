@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sahib/clients"
+	"sahib/model"
+	"sahib/store"
+	"strings"
+	"testing"
+)
+
+// newTestKeyring opens a keyring on the store with the secret, the keys of the
+// environment are ignored.
+func newTestKeyring(t *testing.T, st *store.Store, secret string) *keyring {
+	t.Helper()
+
+	t.Setenv("SAHIB_SECRET", secret)
+	t.Setenv("SAHIB_KEYS", "")
+	for _, provider := range keyProviders {
+		t.Setenv(strings.ToUpper(provider)+"_API_KEY", "")
+	}
+
+	k, err := newKeyring(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func openTestStore(t *testing.T) *store.Store {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "sahib.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestKeyringStore(t *testing.T) {
+	st := openTestStore(t)
+	k := newTestKeyring(t, st, "secret")
+
+	if err := k.setAPIKey("openai", "sk-proj-0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if got := k.APIKey("openai"); got != "sk-proj-0123456789" {
+		t.Errorf("APIKey() = %q", got)
+	}
+	if got := k.statuses()[1]; got != (model.APIKeyStatus{Provider: "openai", Origin: model.KeyFromStore, Hint: "…6789"}) {
+		t.Errorf("status = %+v", got)
+	}
+
+	sealed, err := st.APIKey("openai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed.Ciphertext, []byte("0123456789")) {
+		t.Error("the key is stored in clear")
+	}
+
+	// The key is bound to its provider
+	if err := st.SaveAPIKey("perplexity", *sealed); err != nil {
+		t.Fatal(err)
+	}
+	if got := k.APIKey("perplexity"); got != "" {
+		t.Errorf("the openai key was decrypted as the perplexity one: %q", got)
+	}
+
+	if got := newTestKeyring(t, st, "secret").APIKey("openai"); got != "sk-proj-0123456789" {
+		t.Errorf("APIKey() = %q after a restart", got)
+	}
+	if got := newTestKeyring(t, st, "other secret").APIKey("openai"); got != "" {
+		t.Errorf("APIKey() = %q with another secret", got)
+	}
+
+	if err := k.deleteAPIKey("openai"); err != nil {
+		t.Fatal(err)
+	}
+	if got := k.APIKey("openai"); got != "" {
+		t.Errorf("APIKey() = %q after deleting it", got)
+	}
+}
+
+func TestKeyringWithoutSecret(t *testing.T) {
+	k := newTestKeyring(t, openTestStore(t), "")
+	if k.canStore() {
+		t.Error("the keys can't be stored without SAHIB_SECRET")
+	}
+	if err := k.setAPIKey("openai", "sk-proj-0123456789"); err == nil {
+		t.Error("expected an error without SAHIB_SECRET")
+	}
+}
+
+func TestKeyringOrigins(t *testing.T) {
+	st := openTestStore(t)
+	k := newTestKeyring(t, st, "secret")
+	if err := k.setAPIKey("openai", "from-the-database"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.setAPIKey("perplexity", "from-the-database"); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(file, []byte(`{"openai": "from-the-file", "perplexity": "from-the-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SAHIB_KEYS", file)
+	t.Setenv("PERPLEXITY_API_KEY", "from-the-environment")
+	k, err := newKeyring(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for provider, want := range map[string]string{"perplexity": "from-the-environment", "openai": "from-the-file"} {
+		if got := k.APIKey(provider); got != want {
+			t.Errorf("APIKey(%s) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestKeyringWithKey(t *testing.T) {
+	k := newTestKeyring(t, openTestStore(t), "secret")
+	if err := k.setAPIKey("openai", "server-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	preset := clients.LLMPresets()["openai"]
+	custom := clients.LLMClient{Name: "Local", BaseURL: "http://localhost:1234/v1"}
+	own := clients.LLMPresets()["openai"]
+	own.ApiKey = "own-key"
+
+	tests := []struct {
+		name   string
+		client *clients.LLMClient
+		want   string
+	}{
+		{"preset", &preset, "server-key"},
+		{"custom endpoint", &custom, ""},
+		{"own key", &own, "own-key"},
+	}
+	for _, test := range tests {
+		if got := k.withKey(test.client); got.ApiKey != test.want {
+			t.Errorf("%s: key %q, want %q", test.name, got.ApiKey, test.want)
+		}
+	}
+
+	if preset.ApiKey != "" {
+		t.Error("withKey should return a copy")
+	}
+	if k.withKey(nil) != nil {
+		t.Error("withKey(nil) should be nil")
+	}
+}
@@ -2,43 +2,29 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"sahib/clients"
 	"sahib/components"
+	"sahib/dictionary"
+	"sahib/frequency"
+	"sahib/glossary"
 	"sahib/model"
+	"sahib/store"
+	"sahib/translit"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-type queryFunc func(word string, lang model.Language) (*model.Translations, error)
-
 type source struct {
 	name string
-	fn   queryFunc
-}
-
-func getQueryFunc(name string, apiKey string) (queryFunc, error) {
-	var fn queryFunc
-	switch name {
-	case model.SourceElixir:
-		fn = clients.QueryElixir
-	case model.SourceMaany:
-		fn = clients.QueryMaany
-	case model.SourcePerplexity:
-		if apiKey != "" {
-			client := clients.PerplexityClient{ApiKey: apiKey}
-			fn = client.Query
-		} else {
-			return func(word string, lang model.Language) (*model.Translations, error) {
-				return &model.Translations{}, nil
-			}, nil
-		}
-	default:
-		return nil, fmt.Errorf("unknown source: " + name)
-	}
-
-	return fn, nil
+	fn   clients.QueryFunc
+	// Set for the LLM sources whose answers are streamed instead
+	llm *clients.LLMClient
 }
 
 func handleErr(res *model.Translations, err error, w http.ResponseWriter, msg string, args ...any) (*model.Translations, bool) {
@@ -52,10 +38,146 @@ func handleErr(res *model.Translations, err error, w http.ResponseWriter, msg st
 	return res, false
 }
 
+func formLanguage(r *http.Request) model.Language {
+	langStr := r.FormValue(model.Lang)
+	lang, ok := model.FindLanguage(langStr)
+	if !ok {
+		languages := model.Languages()
+		lang = languages[0]
+		log.Printf("Couldn't find language: %s among %+v (will default to %+v)", langStr, languages, lang)
+	}
+
+	return lang
+}
+
+// promptModes returns the LLM prompt modes selected for a search, the
+// examples by default. The selected user template replaces the default
+// template of its mode.
+func promptModes(r *http.Request, st *store.Store) []clients.PromptMode {
+	modes := []clients.PromptMode{}
+	for _, key := range r.Form[model.Prompt] {
+		if mode, ok := clients.FindPromptMode(key); ok {
+			modes = append(modes, mode)
+		}
+	}
+
+	if len(modes) == 0 {
+		mode, _ := clients.FindPromptMode(clients.PromptExamples)
+		modes = append(modes, mode)
+	}
+
+	id, err := strconv.ParseInt(r.FormValue(model.Template), 10, 64)
+	if err != nil {
+		return modes
+	}
+
+	t, err := st.PromptTemplate(id)
+	if err != nil || t == nil {
+		log.Printf("Couldn't find prompt template %d: %v", id, err)
+		return modes
+	}
+
+	custom, err := clients.WithTemplate(*t)
+	if err != nil {
+		log.Printf("Invalid prompt template %s: %s", t.Name, err)
+		return modes
+	}
+
+	for i, mode := range modes {
+		if mode.Key == custom.Key {
+			modes[i] = custom
+			return modes
+		}
+	}
+
+	return append(modes, custom)
+}
+
+// Maximum size of the documents uploaded to generate glossaries
+const maxUploadSize = 32 << 20
+
 func isSourceEnabled(r *http.Request, source string) bool {
 			return r.FormValue(source) == "on" 
 }
 
+// ranks are the frequencies of the words, nil if no frequency list is imported.
+var ranks *frequency.Ranks
+
+// openDictionaries opens the imported dictionaries, their names are returned
+// in order to be added to the sources after the built-in ones.
+func openDictionaries(path string) ([]string, map[string]clients.QueryFunc, error) {
+	store, err := dictionary.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dicts, err := store.Dictionaries()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := []string{}
+	local := map[string]clients.QueryFunc{}
+	for _, d := range dicts {
+		if slices.Contains(model.AllSources, d.Name) {
+			log.Printf("Skipping dictionary %s: source %s already exists", d.Name, d.Name)
+			continue
+		}
+		names = append(names, d.Name)
+		local[d.Name] = store.Source(d.Name)
+		log.Printf("Loaded dictionary %s (%d entries)", d.Name, d.Entries)
+	}
+	return names, local, nil
+}
+
+// availableSources tells which sources are configured on the server.
+func availableSources(sources []string, config clients.SourceConfig) map[string]bool {
+	available := map[string]bool{}
+	for _, name := range sources {
+		available[name] = config.Available(name)
+	}
+	return available
+}
+
+func transliterate(all []model.TranslationsAndSource, defs *model.Definitions, scheme translit.Scheme) {
+	for _, ts := range all {
+		for i, row := range ts.Translations.List {
+			ts.Translations.List[i].Translit = translit.Transliterate(row.Arabic, scheme)
+		}
+		for i, row := range ts.Translations.Examples {
+			ts.Translations.Examples[i].Translit = translit.Transliterate(row.Arabic, scheme)
+		}
+		for i, entry := range ts.Translations.Monolingual {
+			ts.Translations.Monolingual[i].Translit = translit.Transliterate(entry.Headword, scheme)
+		}
+		for i, entry := range ts.Translations.Wiktionary {
+			ts.Translations.Wiktionary[i].Translit = translit.Transliterate(entry.Word, scheme)
+			for _, sense := range entry.Senses {
+				for j, example := range sense.Examples {
+					sense.Examples[j].Translit = translit.Transliterate(example.Arabic, scheme)
+				}
+			}
+		}
+		for i, root := range ts.Translations.Classical {
+			ts.Translations.Classical[i].Translit = translit.Transliterate(root.Root, scheme)
+			for j, entry := range root.Entries {
+				root.Entries[j].Translit = translit.Transliterate(entry.Word, scheme)
+			}
+		}
+	}
+
+	if defs == nil {
+		return
+	}
+
+	for i, def := range defs.Definitions {
+		defs.Definitions[i].WordTranslit = translit.Transliterate(def.Word, scheme)
+		if def.Root.Valid {
+			defs.Definitions[i].RootTranslit = translit.Transliterate(def.Root.String, scheme)
+		}
+	}
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		panic("Please provide the path to the hans wehr sqlite database")
@@ -67,62 +189,163 @@ func main() {
 		panic(err)
 	}
 
+	if v := os.Getenv("SAHIB_LANGUAGES"); v != "" {
+		if err := model.SetLanguages(strings.Split(v, ",")); err != nil {
+			panic(err)
+		}
+	}
+
+	llm, err := clients.LLMFromEnv("SAHIB_LLM")
+	if err != nil {
+		panic(err)
+	}
+
+	storePath := os.Getenv("SAHIB_DB")
+	if storePath == "" {
+		storePath = "sahib.sqlite"
+	}
+	st, err := store.Open(storePath)
+	if err != nil {
+		panic(err)
+	}
+
+	usage, err := newUsageTracker(st)
+	if err != nil {
+		panic(err)
+	}
+
+	keys, err := newKeyring(st)
+	if err != nil {
+		panic(err)
+	}
+
+	auth, err := newAuth()
+	if err != nil {
+		panic(err)
+	}
+
+	d, err := newDiacritizer(usage)
+	if err != nil {
+		panic(err)
+	}
+	diacritizer = clients.NewCachedDiacritizer(d)
+
+	var wiktionary *clients.Wiktionary
+	if path := os.Getenv("SAHIB_WIKTIONARY"); path != "" {
+		wiktionary, err = clients.NewWiktionaryClient(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var lane *clients.Lane
+	if path := os.Getenv("SAHIB_LANE"); path != "" {
+		lane, err = clients.NewLaneClient(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var tatoeba *clients.Tatoeba
+	if path := os.Getenv("SAHIB_TATOEBA"); path != "" {
+		tatoeba, err = clients.NewTatoebaClient(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if path := os.Getenv("SAHIB_FREQUENCY"); path != "" {
+		ranks, err = frequency.Load(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var quran *clients.Quran
+	if path := os.Getenv("SAHIB_QURAN"); path != "" {
+		quran, err = clients.NewQuranClient(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var dictionaries []string
+	local := map[string]clients.QueryFunc{}
+	if path := os.Getenv("SAHIB_DICTIONARIES"); path != "" {
+		dictionaries, local, err = openDictionaries(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+	allSources := model.Sources(dictionaries)
+
+	local[model.SourceUser] = userDictionary(st)
+
+	// Configuration of the sources with the current keys
+	sourceConfig := func() clients.SourceConfig {
+		config := keys.sourceConfig(llm)
+		config.Wiktionary = wiktionary
+		config.Lane = lane
+		config.Tatoeba = tatoeba
+		config.Local = local
+		return config
+	}
+
 	mainHandler := func(w http.ResponseWriter, r *http.Request) {
-		component := components.Index()
+		templates, err := st.PromptTemplates()
+		if err != nil {
+			log.Printf("Failed to list the prompt templates: %s", err)
+		}
+
+		component := components.Index(templates, allSources, availableSources(allSources, sourceConfig()))
 		component.Render(r.Context(), w)
 	}
-    
-    languages := model.Languages()
 
 	http.HandleFunc("GET /", mainHandler)
 
 	http.HandleFunc("POST /search", func(w http.ResponseWriter, r *http.Request) {
 		search := r.FormValue(model.Search)
-		apiKey := r.FormValue(model.ApiKey)
-        langStr := r.FormValue(model.Lang)
-
-        var lang model.Language
-        for _, l := range languages {
-            if l.Short == langStr {
-                lang = l
-                break
-            }
-        }
-
-        if lang.Short == "" {
-            lang = languages[0]
-            log.Printf("Couldn't find language: %s among %+v (will default to %+v)", langStr, languages, lang)
-        }
 
+        lang := formLanguage(r)
 
 		log.Printf("Searching for: %s (%+v)", search, lang)
 
-		defaultSources := []source{
-			{
-				name: model.SourceElixir,
-				fn:   clients.QueryElixir,
-			},
-			{
-				name: model.SourceMaany,
-				fn:   clients.QueryMaany,
-			},
-		}
+		config := sourceConfig()
+		config.Usage, config.User = usage, auth.identify(r)
+		sources := []source{}
+		llmSources := []source{}
+		denied, skipped := usage.allowed(auth, config.User), false
 
-		if apiKey != "" {
-			client := clients.PerplexityClient{ApiKey: apiKey}
-			defaultSources = append(defaultSources, source{name: model.SourcePerplexity, fn: client.Query})
-		}
+		// Remove disabled sources
+		for _, name := range allSources {
+			if name == model.SourceWehr || !isSourceEnabled(r, name) {
+				continue
+			}
+			if !clients.Supports(name, lang) {
+				log.Printf("Skipping %s which doesn't support %s", name, lang.Name)
+				continue
+			}
 
-		sources := make([]source, 0, len(defaultSources))
+			if client := config.LLMSource(name); client != nil {
+				if denied != nil {
+					skipped = true
+				} else {
+					llmSources = append(llmSources, source{name: name, llm: client})
+				}
+				continue
+			}
 
-		// Remove disabled sources
-		for _, source := range defaultSources {
-			if isSourceEnabled(r, source.name){
-				sources = append(sources, source)
+			fn, err := config.Source(name)
+			if err != nil {
+				log.Printf("Failed to create client for: %s: %s", name, err)
+				continue
 			}
+			sources = append(sources, source{name: name, fn: fn})
 		}
 
 		all := make([]model.TranslationsAndSource, len(sources))
+		scheme := translit.Parse(r.FormValue(model.Translit))
+		annotate := r.FormValue(model.Annotate) == "on"
 
 		var wg sync.WaitGroup
 		for i, src := range sources {
@@ -132,7 +355,7 @@ func main() {
 			go func(idx int) {
 				defer wg.Done()
 				res, err := src.fn(search, lang)
-				res, _ = handleErr(res, err, w, "Failed to create client for: %s: %w", name, err)
+				res, _ = handleErr(res, err, w, "Failed to create client for: %s: %s", name, err)
 				all[idx] = model.TranslationsAndSource{Translations: res, Source: name}
 			}(i)
 		}
@@ -155,10 +378,288 @@ func main() {
 		// TODO: fix error path
 
 		wg.Wait()
+
+		// The LLM answers are streamed once the results are displayed, one card per prompt mode
+		var modes []clients.PromptMode
+		if len(llmSources) > 0 {
+			modes = promptModes(r, st)
+		}
+		for _, src := range llmSources {
+			for _, mode := range modes {
+				name := src.name
+				if mode.Key != clients.PromptExamples {
+					name += " · " + mode.Name
+				}
+				res := &model.Translations{Stream: startStream(&streamJob{
+					source:   name,
+					llm:      src.llm,
+					mode:     mode,
+					word:     search,
+					lang:     lang,
+					scheme:   scheme,
+					annotate: annotate,
+					vocalize: r.FormValue(model.Vocalize) == "on",
+				})}
+				all = append(all, model.TranslationsAndSource{Translations: res, Source: name})
+			}
+		}
+
+		if skipped {
+			res := &model.Translations{Error: denied.Error()}
+			all = append(all, model.TranslationsAndSource{Translations: res, Source: model.SourceLLM})
+		}
+
+		ranks.Annotate(all, defs)
+		if r.FormValue(model.SortFrequency) == "on" {
+			frequency.Sort(all, defs)
+		}
+
+		if scheme != translit.None {
+			transliterate(all, defs, scheme)
+		}
+
+		if r.FormValue(model.Vocalize) == "on" {
+			for _, ts := range all {
+				if vocalizable(ts) {
+					vocalize(ts.Translations, lang, scheme)
+				}
+			}
+		}
+
 		component := components.Results(all, defs)
 		component.Render(r.Context(), w)
 	})
 
+	auth.registerHandlers()
+	registerAdminHandlers(auth, st, llm, usage, keys)
+	registerKeyHandlers(auth, keys)
+	registerUserDictionaryHandlers(auth, st)
+
+	http.HandleFunc("GET /usage", usage.handleUsage(auth))
+
+	// Verses where a word, or its root, occurs
+	http.HandleFunc("GET /quran", func(w http.ResponseWriter, r *http.Request) {
+		search := strings.TrimSpace(r.FormValue(model.Search))
+		byRoot := r.FormValue(model.QuranRoot) == "on"
+		c, errMsg := &model.Concordance{Query: search, ByRoot: byRoot}, ""
+		switch {
+		case quran == nil:
+			errMsg = "the quran corpus isn't imported, see the README"
+		case search != "":
+			var err error
+			c, err = quran.Concordance(search, byRoot)
+			if err != nil {
+				log.Printf("Failed to query the quran for %s: %s", search, err)
+				errMsg = err.Error()
+			}
+		}
+
+		component := components.QuranConcordance(c, errMsg)
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("GET /vocalize/{id}", handleVocalize)
+	http.HandleFunc("GET /stream/{id}", handleStream)
+	http.HandleFunc("POST /stream/{id}/cancel", handleStreamCancel)
+
+	// Refines the Maany card with a category
+	http.HandleFunc("GET /maany", func(w http.ResponseWriter, r *http.Request) {
+		lang := formLanguage(r)
+		res, err := clients.QueryMaanyCategory(r.FormValue(model.Search), lang, r.FormValue(model.CategoryFilter))
+		if err != nil {
+			log.Printf("Failed to query maany: %s", err)
+			components.ResultError(model.SourceMaany, err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		scheme := translit.Parse(r.FormValue(model.Translit))
+		all := []model.TranslationsAndSource{{Translations: res, Source: model.SourceMaany}}
+		if scheme != translit.None {
+			transliterate(all, nil, scheme)
+		}
+		if r.FormValue(model.Vocalize) == "on" {
+			vocalize(res, lang, scheme)
+		}
+
+		component := components.MaanyResult(res)
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("GET /elixir/{mode}", func(w http.ResponseWriter, r *http.Request) {
+		lemma := r.FormValue(model.Word)
+
+		var tables []model.Paradigm
+		var err error
+		switch mode := r.PathValue("mode"); mode {
+		case clients.ElixirInflect:
+			tables, err = clients.InflectElixir(r.Context(), lemma)
+		case clients.ElixirDerive:
+			tables, err = clients.DeriveElixir(r.Context(), lemma)
+		default:
+			http.Error(w, "unknown elixir mode: "+mode, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch elixir %s for %s: %s", r.PathValue("mode"), lemma, err)
+			components.ResultError(model.SourceElixir, err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		component := components.Paradigms(tables)
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("GET /read", func(w http.ResponseWriter, r *http.Request) {
+		component := components.Reader()
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("POST /read", func(w http.ResponseWriter, r *http.Request) {
+		tokens := clients.Tokenize(r.FormValue(model.ReaderText))
+
+		// The same words tend to appear many times in a text, each one is looked up once.
+		words := []string{}
+		for _, tok := range tokens {
+			if tok.Word {
+				words = append(words, tok.Text)
+			}
+		}
+		lookups, err := hansWehr.LookupAll(words)
+		if err != nil {
+			log.Printf("Failed to fetch hans wehr data: %s", err)
+		}
+
+		out := make([]model.ReaderToken, 0, len(tokens))
+		for _, tok := range tokens {
+			if !tok.Word {
+				out = append(out, model.ReaderToken{Text: tok.Text})
+				continue
+			}
+
+			res := model.ReaderToken{Text: tok.Text, Word: true}
+			if l, ok := lookups[tok.Text]; ok && len(l.Definitions.Definitions) > 0 {
+				res.Match = l.Match
+				res.Gloss = clients.Gloss(l.Definitions.Definitions[0].Definition, 120)
+			}
+			out = append(out, res)
+		}
+
+		component := components.ReaderText(out)
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("GET /read/word", func(w http.ResponseWriter, r *http.Request) {
+		word := r.FormValue(model.Word)
+		defs, match, err := hansWehr.Lookup(word)
+		if err != nil {
+			log.Printf("Failed to fetch hans wehr data for %s: %s", word, err)
+			defs = &model.Definitions{}
+		}
+
+		gloss := ""
+		if len(defs.Definitions) > 0 {
+			gloss = clients.Gloss(defs.Definitions[0].Definition, 120)
+		}
+
+		component := components.ReaderWord(word, match, gloss, defs)
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("GET /glossary", func(w http.ResponseWriter, r *http.Request) {
+		component := components.GlossaryPage(glossary.Formats, allSources, availableSources(allSources, sourceConfig()))
+		component.Render(r.Context(), w)
+	})
+
+	http.HandleFunc("POST /glossary", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse upload: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile(model.File)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Missing document: %s", err), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read document: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		text, err := glossary.ExtractText(header.Filename, content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		vocabulary, err := glossary.ParseVocabulary(strings.NewReader(r.FormValue(model.Vocabulary)))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid vocabulary: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		top, err := strconv.Atoi(r.FormValue(model.Top))
+		if err != nil || top <= 0 {
+			top = 50
+		}
+
+		lang := formLanguage(r)
+
+		config := sourceConfig()
+		config.Usage, config.User = usage, auth.identify(r)
+		opts := glossary.Options{
+			Top:             top,
+			Config:          config,
+			Lang:            lang,
+			Vocabulary:      vocabulary,
+			Ranks:           ranks,
+			SortByFrequency: r.FormValue(model.SortFrequency) == "on",
+		}
+		denied := usage.allowed(auth, config.User)
+		for _, name := range allSources {
+			if name == model.SourceWehr || !isSourceEnabled(r, name) || !clients.Supports(name, lang) {
+				continue
+			}
+			if denied != nil && config.LLMSource(name) != nil {
+				log.Printf("Skipping %s: %s", name, denied)
+				continue
+			}
+			opts.Sources = append(opts.Sources, name)
+		}
+
+		log.Printf("Building glossary for %s (%+v)", header.Filename, opts.Sources)
+		entries, err := glossary.Build(text, hansWehr, opts)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to build glossary: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		format := r.FormValue(model.Format)
+		switch format {
+		case glossary.FormatCSV:
+			w.Header().Set("Content-Disposition", `attachment; filename="glossary.csv"`)
+			w.Header().Set(clients.ContentType, "text/csv; charset=utf-8")
+		case glossary.FormatAnki:
+			w.Header().Set("Content-Disposition", `attachment; filename="glossary.txt"`)
+			w.Header().Set(clients.ContentType, "text/plain; charset=utf-8")
+		case glossary.FormatHTML:
+			w.Header().Set("Content-Disposition", `attachment; filename="glossary.html"`)
+			w.Header().Set(clients.ContentType, "text/html; charset=utf-8")
+		}
+
+		if format == glossary.FormatHTML {
+			err = components.Glossary(entries).Render(r.Context(), w)
+		} else {
+			err = glossary.Write(w, format, entries)
+		}
+		if err != nil {
+			log.Printf("Failed to write glossary: %s", err)
+		}
+	})
+
 	log.Print("Listening...")
 	log.Fatal(http.ListenAndServe(":8081", nil))
 }
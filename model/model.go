@@ -2,6 +2,10 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
@@ -9,23 +13,57 @@ const (
 	SourceElixir     = "Elixir"
 	SourceMaany      = "Maany"
 	SourcePerplexity = "Perplexity"
+	SourceLLM        = "LLM"
+	SourceMaanyArabic = "MaanyArabic"
+	SourceWiktionary = "Wiktionary"
+	SourceUser = "MyDictionary"
+	SourceLane = "Lane"
+	SourceTatoeba = "Tatoeba"
 
 	ApiKey = "apiKey"
 	Search = "search"
     Lang= "lang"
+	Translit = "translit"
+	Vocalize = "vocalize"
+	Prompt = "prompt"
+	Template = "template"
+	ReaderText = "text"
+	Word = "word"
+	File = "file"
+	Top = "top"
+	Format = "format"
+	Vocabulary = "vocabulary"
+	Annotate = "annotate"
+	CategoryFilter = "category"
+	QuranRoot = "root"
+	SortFrequency = "sortFrequency"
+	CSRF = "csrf"
+	Token = "token"
+	Next = "next"
 )
 
 var AllSources = []string{
+    SourceUser,
     SourceWehr,
     SourceElixir,
     SourceMaany,
+    SourceMaanyArabic,
+    SourceWiktionary,
+    SourceLane,
+    SourceTatoeba,
     SourcePerplexity,
+    SourceLLM,
 }
 
-func SourceAndLangIds() []string {
+// Sources returns the built-in sources followed by the local dictionaries.
+func Sources(local []string) []string {
+	return append(slices.Clone(AllSources), local...)
+}
+
+func SourceAndLangIds(sources []string) []string {
     languages := Languages()
-    r := make([]string, 0, len(AllSources) + len(languages))
-    for _, src := range AllSources {
+    r := make([]string, 0, len(sources) + len(languages))
+    for _, src := range sources {
         r = append(r, "#" + src)
     }
     for _, lang := range languages {
@@ -35,21 +73,72 @@ func SourceAndLangIds() []string {
     return r
 }
 
+// AllLanguages returns every known translation language, the ones offered
+// to the users can be chosen with SetLanguages.
+func AllLanguages() []Language {
+	return []Language{
+		{Short: "lang_fr", Code: "fr", Name: "French", Logo: "🇫🇷"},
+		{Short: "lang_en", Code: "en", Name: "English", Logo: "🇬🇧"},
+		{Short: "lang_es", Code: "es", Name: "Spanish", Logo: "🇪🇸"},
+		{Short: "lang_de", Code: "de", Name: "German", Logo: "🇩🇪"},
+		{Short: "lang_tr", Code: "tr", Name: "Turkish", Logo: "🇹🇷"},
+		{Short: "lang_it", Code: "it", Name: "Italian", Logo: "🇮🇹"},
+		{Short: "lang_pt", Code: "pt", Name: "Portuguese", Logo: "🇵🇹"},
+		{Short: "lang_ru", Code: "ru", Name: "Russian", Logo: "🇷🇺"},
+		{Short: "lang_id", Code: "id", Name: "Indonesian", Logo: "🇮🇩"},
+		{Short: "lang_ms", Code: "ms", Name: "Malay", Logo: "🇲🇾"},
+		{Short: "lang_fa", Code: "fa", Name: "Persian", Logo: "🇮🇷"},
+		{Short: "lang_ur", Code: "ur", Name: "Urdu", Logo: "🇵🇰"},
+		// Monolingual, the definitions are in arabic
+		{Short: "lang_ar", Code: "ar", Name: "Arabic", Logo: "🇸🇦"},
+	}
+}
+
+// By default only the languages the original versions offered.
+var languages = []string{"fr", "en"}
+
+// SetLanguages sets the codes of the languages offered to the users, the
+// first one is the default.
+func SetLanguages(codes []string) error {
+	if len(codes) == 0 {
+		return fmt.Errorf("at least one language is required")
+	}
+
+	for _, code := range codes {
+		if _, ok := findLanguage(AllLanguages(), code); !ok {
+			return fmt.Errorf("unknown language: %s", code)
+		}
+	}
+
+	languages = codes
+	return nil
+}
+
+// Languages returns the languages offered to the users, the first one being the default.
 func Languages() []Language {
-    return []Language{
-        {
-            Short: "lang_fr",
-            Code: "fr",
-            Name: "French",
-            Logo:  "🇫🇷",
-        },
-        {
-            Short: "lang_en",
-            Code: "en",
-            Name: "English",
-            Logo:  "🇬🇧",
-        },
-    }
+	all := AllLanguages()
+	out := make([]Language, 0, len(languages))
+	for _, code := range languages {
+		if lang, ok := findLanguage(all, code); ok {
+			out = append(out, lang)
+		}
+	}
+	return out
+}
+
+func findLanguage(languages []Language, name string) (Language, bool) {
+	for _, l := range languages {
+		if l.Short == name || l.Code == name {
+			return l, true
+		}
+	}
+
+	return Language{}, false
+}
+
+// FindLanguage returns the language with the given short name or code.
+func FindLanguage(name string) (Language, bool) {
+	return findLanguage(Languages(), name)
 }
 
 type Language struct {
@@ -69,12 +158,182 @@ type Translations struct {
 	Link    string
 	List    []Translation
 	Error   string
+	// Id of the background job adding the diacritics to List, if any.
+	Pending string
+	// Id of the job streaming the results, if any.
+	Stream string
+
+	// Sections of the dictionaries that have more than translations (Maany)
+	Examples   []Translation
+	Related    []RelatedWord
+	Categories []Category
+	// Selected category, empty for all of them
+	Category string
+	// Searched word and language code, to refine the search from the card
+	Word string
+	Lang string
+	// Entries of the monolingual dictionaries
+	Monolingual []ArabicDefinition
+	Wiktionary  []WiktionaryEntry
+	Classical   []ClassicalRoot
+}
+
+// ClassicalRoot is a root of a classical dictionary (Lane's Lexicon...) with
+// its entries, and the roots before and after it to browse the dictionary.
+type ClassicalRoot struct {
+	Root     string
+	Translit string
+	Volume   int
+	Page     int
+	Entries  []ClassicalEntry
+	Previous string
+	Next     string
+}
+
+// PageReference returns the volume and page of the root, e.g. "vol. 1 p. 7".
+func (r ClassicalRoot) PageReference() string {
+	return pageReference(r.Volume, r.Page)
+}
+
+type ClassicalEntry struct {
+	Word       string
+	Translit   string
+	Definition string
+	Volume     int
+	Page       int
+	// Whether it is the entry of the searched word
+	Matched bool
+}
+
+// PageReference returns the volume and page of the entry, e.g. "vol. 1 p. 7".
+func (e ClassicalEntry) PageReference() string {
+	return pageReference(e.Volume, e.Page)
+}
+
+// pageReference is empty for the dictionaries without page numbers.
+func pageReference(volume int, page int) string {
+	switch {
+	case volume > 0 && page > 0:
+		return fmt.Sprintf("vol. %d p. %d", volume, page)
+	case page > 0:
+		return fmt.Sprintf("p. %d", page)
+	}
+	return ""
+}
+
+// ArabicDefinition is an entry of a monolingual arabic dictionary.
+type ArabicDefinition struct {
+	Headword    string
+	Translit    string
+	Definitions []string
+	Examples    []string
+}
+
+type RelatedWord struct {
+	Arabic string
+	Link   string
+}
+
+// Category of a dictionary, e.g. a domain (medicine, law...) or a part of speech.
+type Category struct {
+	Name  string
+	Value string
 }
 
 type Translation struct {
 	Arabic      string
 	Translation string
 	Meta        string
+	// Romanization of Arabic, only set when a transliteration scheme is selected.
+	Translit string
+	// Words of the arabic column with their morphology, only set when the
+	// sentences are annotated.
+	Words []AnnotatedWord
+	// Structured analysis of the word, only set by the morphological sources
+	Morphology *Morphology
+	// Only set for single words found in the frequency list
+	Frequency Frequency
+}
+
+// Frequency bands, from the most to the least common words.
+const (
+	BandVeryCommon = "very common"
+	BandCommon     = "common"
+	BandUncommon   = "uncommon"
+	BandRare       = "rare"
+)
+
+// Frequency is the rank of a word in the frequency list, 1 being the most
+// common word, and its band. The rank is 0 if the word isn't in the list.
+type Frequency struct {
+	Rank int
+	Band string
+}
+
+// Known returns whether the word is in the frequency list.
+func (f Frequency) Known() bool {
+	return f.Rank > 0
+}
+
+// Morphology is the analysis of a lemma by Elixir FM.
+type Morphology struct {
+	Lemma   string
+	Root    string
+	Pattern string
+	Gloss   string
+	// Positional tag of the lemma, e.g. N------S-- (see TagFeatures)
+	Tag      string
+	Features TagFeatures
+	// Inflected forms of the lemma matching the searched word
+	Forms []InflectedForm
+}
+
+type InflectedForm struct {
+	Arabic   string
+	Tag      string
+	Features TagFeatures
+}
+
+// Paradigm is a table of inflected or derived forms sharing their part of
+// speech, aspect, mood and voice.
+type Paradigm struct {
+	Title string
+	Forms []InflectedForm
+}
+
+// TagFeatures are the decoded positions of an Elixir FM tag, the ones that
+// don't apply are empty.
+type TagFeatures struct {
+	POS    string
+	Aspect string
+	Mood   string
+	Voice  string
+	Person string
+	Gender string
+	Number string
+	Case   string
+	State  string
+}
+
+// String returns the non empty features, e.g. "verb, imperfective, indicative, active, 3rd person".
+func (f TagFeatures) String() string {
+	parts := []string{}
+	for _, v := range []string{f.POS, f.Aspect, f.Mood, f.Voice, f.Person, f.Gender, f.Number, f.Case, f.State} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AnnotatedWord is a token of a sentence, with the morphology found by elixir
+// if it is an arabic word.
+type AnnotatedWord struct {
+	Text string
+	Word bool
+	Lemma string
+	Tag string
+	Gloss string
 }
 
 type Definitions struct {
@@ -88,4 +347,141 @@ type Definition struct {
 	Root       sql.NullString
 	RootDef    sql.NullString
 	QuranCount sql.NullInt64
+
+	WordTranslit string
+	RootTranslit string
+
+	Frequency Frequency
+}
+
+// Concordance lists the verses of the Quran where a word, or a root,
+// occurs.
+type Concordance struct {
+	Query  string
+	ByRoot bool
+	// Root of the first occurrence, to look for the other forms
+	Root string
+	// Number of occurrences, a verse can have several
+	Occurrences int
+	Verses      []QuranVerse
+}
+
+type QuranVerse struct {
+	Sura  int
+	Aya   int
+	Words []QuranWord
+}
+
+type QuranWord struct {
+	Text  string
+	Match bool
+}
+
+type ReaderToken struct {
+	Text string
+	// Whether the token is an arabic word (as opposed to spaces, punctuation...)
+	Word bool
+	// Form of the word found in the dictionary, empty if the word is unknown.
+	Match string
+	Gloss string
+}
+
+type GlossaryEntry struct {
+	// Normalized word as found in the text
+	Word  string
+	Count int
+	// Form of the word found in Hans Wehr
+	Match        string
+	Definition   string
+	Translations []TranslationsAndSource
+	Frequency    Frequency
+}
+
+// PromptTemplate is a prompt written by the users to replace the default
+// template of an LLM prompt mode.
+type PromptTemplate struct {
+	ID        int64
+	Name      string
+	Mode      string
+	Template  string
+	// Number of items asked to the model
+	Count     int
+	UpdatedAt time.Time
+}
+
+// UserEntry is an entry of the dictionary edited by the users, the language
+// is empty if the entry is shown with every language.
+type UserEntry struct {
+	ID          int64     `json:"id"`
+	Arabic      string    `json:"arabic"`
+	Translation string    `json:"translation"`
+	Language    string    `json:"language"`
+	Domain      string    `json:"domain"`
+	Notes       string    `json:"notes"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LLMUsage is the number of tokens consumed by an LLM request and its estimated cost.
+type LLMUsage struct {
+	Provider         string
+	Model            string
+	User             string
+	PromptTokens     int
+	CompletionTokens int
+	// In dollars
+	Cost      float64
+	CreatedAt time.Time
+}
+
+// UsageTotal sums the LLM usage of a day, a user...
+type UsageTotal struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+const (
+	KeyFromEnv   = "environment"
+	KeyFromFile  = "config file"
+	KeyFromStore = "database"
+)
+
+// APIKeyStatus tells if the API key of a provider is configured on the
+// server, without the key itself.
+type APIKeyStatus struct {
+	Provider string
+	// Where the key comes from, empty if there is none
+	Origin string
+	// Last characters of the key
+	Hint string
+}
+
+// WiktionaryEntry is an arabic word of the wiktionary, one per part of speech.
+type WiktionaryEntry struct {
+	Word          string
+	POS           string
+	Root          string
+	// Lemma of the inflected forms, empty for the lemmas themselves
+	Lemma         string
+	Etymology     string
+	Pronunciation string
+	Romanization  string
+	// Romanization with the selected transliteration scheme
+	Translit      string
+	Senses        []WiktionarySense
+	// Inflections (plural, feminine, verb forms...)
+	Forms []WiktionaryForm
+}
+
+type WiktionarySense struct {
+	Gloss    string
+	Tags     []string
+	Examples []Translation
+}
+
+type WiktionaryForm struct {
+	Form string
+	Tags []string
 }
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+)
+
+const userEntryColumns = `id, arabic, translation, language, domain, notes, updated_at`
+
+func normalizeEntry(arabic string) string {
+	return clients.NormalizeAlef(clients.Normalize(strings.TrimSpace(arabic)))
+}
+
+func scanUserEntries(rows *sql.Rows) ([]model.UserEntry, error) {
+	defer rows.Close()
+
+	entries := []model.UserEntry{}
+	for rows.Next() {
+		e := model.UserEntry{}
+		if err := rows.Scan(&e.ID, &e.Arabic, &e.Translation, &e.Language, &e.Domain, &e.Notes, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error while scanning user entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// UserEntries returns the entries of the user dictionary, the ones whose
+// arabic, translation or domain contain the query if it isn't empty.
+func (s *Store) UserEntries(query string) ([]model.UserEntry, error) {
+	like := "%" + strings.TrimSpace(query) + "%"
+	rows, err := s.db.Query(`SELECT `+userEntryColumns+` FROM user_entries
+        WHERE normalized LIKE ? OR translation LIKE ? OR domain LIKE ? ORDER BY normalized, id`,
+		"%"+normalizeEntry(query)+"%", like, like)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user entries: %w", err)
+	}
+
+	return scanUserEntries(rows)
+}
+
+// MatchUserEntries returns the entries of the word, without its diacritics,
+// for the language.
+func (s *Store) MatchUserEntries(word string, lang string) ([]model.UserEntry, error) {
+	rows, err := s.db.Query(`SELECT `+userEntryColumns+` FROM user_entries
+        WHERE normalized = ? AND (language = '' OR language = ?) ORDER BY id`, normalizeEntry(word), lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match user entries: %w", err)
+	}
+
+	return scanUserEntries(rows)
+}
+
+// UserEntry returns the entry with the given id, nil if it doesn't exist.
+func (s *Store) UserEntry(id int64) (*model.UserEntry, error) {
+	e := model.UserEntry{}
+	err := s.db.QueryRow(`SELECT `+userEntryColumns+` FROM user_entries WHERE id = ?`, id).
+		Scan(&e.ID, &e.Arabic, &e.Translation, &e.Language, &e.Domain, &e.Notes, &e.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user entry %d: %w", id, err)
+	}
+
+	return &e, nil
+}
+
+// SaveUserEntry creates the entry if its ID is 0 and updates it otherwise.
+func (s *Store) SaveUserEntry(e *model.UserEntry) error {
+	if e.ID == 0 {
+		res, err := s.db.Exec(`INSERT INTO user_entries (arabic, normalized, translation, language, domain, notes) VALUES (?, ?, ?, ?, ?, ?)`,
+			e.Arabic, normalizeEntry(e.Arabic), e.Translation, e.Language, e.Domain, e.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to create user entry: %w", err)
+		}
+		e.ID, err = res.LastInsertId()
+		return err
+	}
+
+	res, err := s.db.Exec(`UPDATE user_entries SET arabic = ?, normalized = ?, translation = ?, language = ?, domain = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		e.Arabic, normalizeEntry(e.Arabic), e.Translation, e.Language, e.Domain, e.Notes, e.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user entry %d: %w", e.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user entry %d doesn't exist", e.ID)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteUserEntry(id int64) error {
+	if _, err := s.db.Exec(`DELETE FROM user_entries WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete user entry %d: %w", id, err)
+	}
+
+	return nil
+}
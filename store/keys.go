@@ -0,0 +1,48 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SealedKey is an API key encrypted by the caller, the store never sees the
+// keys in clear.
+type SealedKey struct {
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+func (s *Store) SaveAPIKey(provider string, key SealedKey) error {
+	_, err := s.db.Exec(`INSERT INTO api_keys (provider, nonce, ciphertext) VALUES (?, ?, ?)
+        ON CONFLICT(provider) DO UPDATE SET nonce = excluded.nonce, ciphertext = excluded.ciphertext, updated_at = CURRENT_TIMESTAMP`,
+		provider, key.Nonce, key.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to save %s API key: %w", provider, err)
+	}
+
+	return nil
+}
+
+// APIKey returns the sealed key of the provider, nil if there is none.
+func (s *Store) APIKey(provider string) (*SealedKey, error) {
+	key := SealedKey{}
+	err := s.db.QueryRow(`SELECT nonce, ciphertext FROM api_keys WHERE provider = ?`, provider).
+		Scan(&key.Nonce, &key.Ciphertext)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s API key: %w", provider, err)
+	}
+
+	return &key, nil
+}
+
+func (s *Store) DeleteAPIKey(provider string) error {
+	if _, err := s.db.Exec(`DELETE FROM api_keys WHERE provider = ?`, provider); err != nil {
+		return fmt.Errorf("failed to delete %s API key: %w", provider, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sahib/model"
+)
+
+func (s *Store) PromptTemplates() ([]model.PromptTemplate, error) {
+	rows, err := s.db.Query(`SELECT id, name, mode, template, count, updated_at FROM prompt_templates ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []model.PromptTemplate{}
+	for rows.Next() {
+		t := model.PromptTemplate{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.Mode, &t.Template, &t.Count, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error while scanning prompt template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+// PromptTemplate returns the template with the given id, nil if it doesn't exist.
+func (s *Store) PromptTemplate(id int64) (*model.PromptTemplate, error) {
+	t := model.PromptTemplate{}
+	err := s.db.QueryRow(`SELECT id, name, mode, template, count, updated_at FROM prompt_templates WHERE id = ?`, id).
+		Scan(&t.ID, &t.Name, &t.Mode, &t.Template, &t.Count, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template %d: %w", id, err)
+	}
+
+	return &t, nil
+}
+
+// SavePromptTemplate creates the template if its ID is 0 and updates it otherwise.
+func (s *Store) SavePromptTemplate(t *model.PromptTemplate) error {
+	if t.ID == 0 {
+		res, err := s.db.Exec(`INSERT INTO prompt_templates (name, mode, template, count) VALUES (?, ?, ?, ?)`,
+			t.Name, t.Mode, t.Template, t.Count)
+		if err != nil {
+			return fmt.Errorf("failed to create prompt template: %w", err)
+		}
+		t.ID, err = res.LastInsertId()
+		return err
+	}
+
+	_, err := s.db.Exec(`UPDATE prompt_templates SET name = ?, mode = ?, template = ?, count = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		t.Name, t.Mode, t.Template, t.Count, t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update prompt template %d: %w", t.ID, err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeletePromptTemplate(id int64) error {
+	if _, err := s.db.Exec(`DELETE FROM prompt_templates WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete prompt template %d: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"fmt"
+	"sahib/model"
+	"time"
+)
+
+// Format of CURRENT_TIMESTAMP, the dates are compared as strings by sqlite.
+const timestampFormat = "2006-01-02 15:04:05"
+
+// AddUsage records the usage of an LLM request, its cost must already be computed.
+func (s *Store) AddUsage(u model.LLMUsage) error {
+	_, err := s.db.Exec(`INSERT INTO llm_usage (provider, model, user, prompt_tokens, completion_tokens, cost) VALUES (?, ?, ?, ?, ?, ?)`,
+		u.Provider, u.Model, u.User, u.PromptTokens, u.CompletionTokens, u.Cost)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	return nil
+}
+
+// usageTotals sums the usage since the given time by groupBy, only the one of
+// the user if not empty.
+func (s *Store) usageTotals(groupBy string, user string, since time.Time) ([]model.UsageTotal, error) {
+	rows, err := s.db.Query(
+		`SELECT `+groupBy+` AS key, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(cost)
+        FROM llm_usage WHERE created_at >= ?1 AND (?2 = '' OR user = ?2) GROUP BY key ORDER BY key DESC`,
+		since.UTC().Format(timestampFormat), user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum usage: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []model.UsageTotal{}
+	for rows.Next() {
+		t := model.UsageTotal{}
+		if err := rows.Scan(&t.Key, &t.Requests, &t.PromptTokens, &t.CompletionTokens, &t.Cost); err != nil {
+			return nil, fmt.Errorf("error while scanning usage: %w", err)
+		}
+		totals = append(totals, t)
+	}
+
+	return totals, rows.Err()
+}
+
+// UsageByDay returns the usage of each day (UTC) since the given time, the most recent first.
+func (s *Store) UsageByDay(since time.Time) ([]model.UsageTotal, error) {
+	return s.usageTotals("date(created_at)", "", since)
+}
+
+// UsageByUser returns the usage of each user since the given time.
+func (s *Store) UsageByUser(since time.Time) ([]model.UsageTotal, error) {
+	return s.usageTotals("user", "", since)
+}
+
+// UserUsageByDay returns the usage of a user on each day (UTC) since the given
+// time, the most recent first.
+func (s *Store) UserUsageByDay(user string, since time.Time) ([]model.UsageTotal, error) {
+	return s.usageTotals("date(created_at)", user, since)
+}
+
+// UserUsage returns the usage of a user since the given time, in a single
+// total if they made any request.
+func (s *Store) UserUsage(user string, since time.Time) ([]model.UsageTotal, error) {
+	return s.usageTotals("user", user, since)
+}
+
+// UserUsageCost returns the cost of the requests of a user made since the given time.
+func (s *Store) UserUsageCost(user string, since time.Time) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(cost), 0) FROM llm_usage WHERE user = ? AND created_at >= ?`,
+		user, since.UTC().Format(timestampFormat)).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage cost of %s: %w", user, err)
+	}
+
+	return cost, nil
+}
+
+// UsageCost returns the cost of the requests made since the given time.
+func (s *Store) UsageCost(since time.Time) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(cost), 0) FROM llm_usage WHERE created_at >= ?`,
+		since.UTC().Format(timestampFormat)).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage cost: %w", err)
+	}
+
+	return cost, nil
+}
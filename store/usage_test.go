@@ -0,0 +1,78 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"sahib/model"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "sahib.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestUsageTotals(t *testing.T) {
+	s := newTestStore(t)
+	for _, u := range []model.LLMUsage{
+		{Provider: "OpenAI", Model: "gpt-4o-mini", User: "amina", PromptTokens: 100, CompletionTokens: 20, Cost: 0.5},
+		{Provider: "OpenAI", Model: "gpt-4o-mini", User: "amina", PromptTokens: 50, CompletionTokens: 10, Cost: 0.25},
+		{Provider: "OpenAI", Model: "gpt-4o-mini", User: "omar", PromptTokens: 10, CompletionTokens: 1, Cost: 2},
+		{Provider: "llama.cpp", Model: "", User: "", PromptTokens: 7, CompletionTokens: 3},
+	} {
+		if err := s.AddUsage(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Requests of the previous month
+	if _, err := s.db.Exec(`INSERT INTO llm_usage (provider, model, user, prompt_tokens, completion_tokens, cost, created_at)
+        VALUES ('OpenAI', 'gpt-4o', 'amina', 1, 1, 8, '2000-01-01 10:00:00')`); err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	users, err := s.UsageByUser(since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.UsageTotal{
+		{Key: "omar", Requests: 1, PromptTokens: 10, CompletionTokens: 1, Cost: 2},
+		{Key: "amina", Requests: 2, PromptTokens: 150, CompletionTokens: 30, Cost: 0.75},
+		{Key: "", Requests: 1, PromptTokens: 7, CompletionTokens: 3},
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("UsageByUser() = %+v, want %+v", users, want)
+	}
+
+	amina, err := s.UserUsage("amina", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(amina, want[1:2]) {
+		t.Errorf("UserUsage() = %+v, want %+v", amina, want[1:2])
+	}
+
+	days, err := s.UserUsageByDay("omar", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0].Requests != 1 || days[0].Cost != 2 {
+		t.Errorf("UserUsageByDay() = %+v", days)
+	}
+
+	for user, want := range map[string]float64{"amina": 0.75, "omar": 2, "nobody": 0} {
+		if cost, err := s.UserUsageCost(user, since); err != nil || cost != want {
+			t.Errorf("UserUsageCost(%s) = %v, %v, want %v", user, cost, err, want)
+		}
+	}
+	if cost, err := s.UsageCost(time.Time{}); err != nil || cost != 10.75 {
+		t.Errorf("UsageCost() = %v, %v, want 10.75", cost, err)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/translit"
+	"strings"
+	"sync"
+	"time"
+)
+
+func newJobID() string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
+
+// streamJob is an LLM query whose answer is streamed to the results page
+// with server sent events.
+type streamJob struct {
+	source string
+	llm    *clients.LLMClient
+	mode   clients.PromptMode
+	word   string
+	lang   model.Language
+	scheme translit.Scheme
+	// Whether the rows are annotated with their morphology once received
+	annotate bool
+	// Whether the diacritics are added to the final rows
+	vocalize bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	// Set if the job is cancelled before the browser connects to the stream
+	cancelled bool
+	// Set once a connection runs the job
+	claimed bool
+}
+
+// claim marks the job as run by the connection, it returns false if another
+// connection already runs it: the EventSource reconnecting in the middle of
+// the stream would ask the model again. cancelled is true if the job was
+// cancelled before.
+func (job *streamJob) claim(cancel context.CancelFunc) (claimed bool, cancelled bool) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.claimed {
+		return false, job.cancelled
+	}
+	job.claimed = true
+	job.cancel = cancel
+	return true, job.cancelled
+}
+
+var streamJobs = struct {
+	sync.Mutex
+	m map[string]*streamJob
+}{m: map[string]*streamJob{}}
+
+// How long a stream job is kept if the browser never connects to it.
+const streamJobTTL = 5 * time.Minute
+
+// startStream registers the job, it runs once the browser connects to its stream.
+func startStream(job *streamJob) string {
+	id := newJobID()
+	streamJobs.Lock()
+	streamJobs.m[id] = job
+	streamJobs.Unlock()
+
+	time.AfterFunc(streamJobTTL, func() {
+		streamJobs.Lock()
+		delete(streamJobs.m, id)
+		streamJobs.Unlock()
+	})
+
+	return id
+}
+
+type component interface {
+	Render(ctx context.Context, w io.Writer) error
+}
+
+func sendEvent(w http.ResponseWriter, event string, c component) error {
+	var buf bytes.Buffer
+	if err := c.Render(context.Background(), &buf); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	_, err := fmt.Fprint(w, "\n")
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return err
+}
+
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	streamJobs.Lock()
+	job, ok := streamJobs.m[id]
+	streamJobs.Unlock()
+
+	if !ok {
+		// No content makes the browser stop reconnecting
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// The upstream request is aborted if the user cancels or leaves the page.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	claimed, cancelled := job.claim(cancel)
+	if !claimed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	defer func() {
+		streamJobs.Lock()
+		delete(streamJobs.m, id)
+		streamJobs.Unlock()
+	}()
+
+	w.Header().Set(clients.ContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if cancelled {
+		// Nothing was asked to the model, the card is removed
+		if err := sendEvent(w, "done", components.Result(job.source, "", "cancelled", nil, "")); err != nil {
+			log.Printf("Failed to send streamed result: %s", err)
+		}
+		return
+	}
+
+	withTranslit := func(row model.Translation) model.Translation {
+		if job.scheme != translit.None {
+			row.Translit = translit.Transliterate(row.Arabic, job.scheme)
+		}
+		row.Frequency = ranks.Of(row.Arabic)
+		return row
+	}
+
+	res, err := job.llm.QueryStream(ctx, job.mode, job.word, job.lang, func(row model.Translation) {
+		if err := sendEvent(w, "row", components.ResultRow(withTranslit(row))); err != nil {
+			log.Printf("Failed to send streamed row: %s", err)
+		}
+	})
+
+	if r.Context().Err() != nil {
+		// The browser is gone, nobody to send the result to.
+		return
+	}
+
+	for i, row := range res.List {
+		res.List[i] = withTranslit(row)
+	}
+
+	// The streamed rows stay displayed while the words are resolved.
+	if job.annotate && err == nil {
+		res.List = clients.Annotate(ctx, res.List)
+	}
+
+	// Like the other results, the rows are swapped once vocalized. The
+	// annotated rows show their words instead of the arabic column.
+	if job.vocalize && !job.annotate && (err == nil || ctx.Err() != nil) {
+		vocalize(res, job.lang, job.scheme)
+	}
+
+	var done component
+	switch {
+	case ctx.Err() != nil:
+		done = components.Result(job.source, "", res.Elapsed+", cancelled", res.List, res.Pending)
+	case err != nil:
+		log.Printf("Failed to stream %s answer for %s: %s", job.source, job.word, err)
+		done = components.ResultError(job.source, err.Error())
+	default:
+		done = components.Result(job.source, res.Link, res.Elapsed, res.List, res.Pending)
+	}
+
+	if err := sendEvent(w, "done", done); err != nil {
+		log.Printf("Failed to send streamed result: %s", err)
+	}
+}
+
+func handleStreamCancel(w http.ResponseWriter, r *http.Request) {
+	streamJobs.Lock()
+	job, ok := streamJobs.m[r.PathValue("id")]
+	streamJobs.Unlock()
+
+	if ok {
+		job.mu.Lock()
+		if job.cancel != nil {
+			job.cancel()
+		} else {
+			job.cancelled = true
+		}
+		job.mu.Unlock()
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
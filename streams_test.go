@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sahib/clients"
+	"sahib/model"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getStream(id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/stream/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handleStream(rec, req)
+	return rec
+}
+
+func TestStreamReconnect(t *testing.T) {
+	var streams atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request["stream"] != true {
+			// The repair request of the empty answer
+			w.Write([]byte(`{"choices": []}`))
+			return
+		}
+
+		if streams.Add(1) == 1 {
+			close(started)
+		}
+		w.Header().Set(clients.ContentType, "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	mode, _ := clients.FindPromptMode(clients.PromptExamples)
+	id := startStream(&streamJob{
+		source: "LLM",
+		llm:    &clients.LLMClient{Name: "Test", BaseURL: server.URL},
+		mode:   mode,
+		word:   "كتب",
+		lang:   model.Language{Code: "en", Name: "English"},
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var first *httptest.ResponseRecorder
+	go func() {
+		defer wg.Done()
+		first = getStream(id)
+	}()
+
+	// The EventSource reconnects while the answer is streamed
+	<-started
+	reconnect := make(chan int, 1)
+	go func() {
+		reconnect <- getStream(id).Code
+	}()
+	select {
+	case code := <-reconnect:
+		if code != http.StatusNoContent {
+			t.Errorf("reconnecting returned %d, want %d", code, http.StatusNoContent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("reconnecting runs the job again")
+	}
+
+	close(release)
+	wg.Wait()
+	if first.Code != http.StatusOK {
+		t.Errorf("the stream returned %d", first.Code)
+	}
+	if n := streams.Load(); n != 1 {
+		t.Errorf("the model was asked %d times, want 1", n)
+	}
+
+	// The finished job is forgotten
+	if rec := getStream(id); rec.Code != http.StatusNoContent {
+		t.Errorf("the finished stream returned %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestStreamCancelledBeforeConnecting(t *testing.T) {
+	id := startStream(&streamJob{source: "LLM", llm: &clients.LLMClient{Name: "Test", BaseURL: "http://127.0.0.1:1"}})
+
+	req := httptest.NewRequest("POST", "/stream/"+id+"/cancel", nil)
+	req.SetPathValue("id", id)
+	handleStreamCancel(httptest.NewRecorder(), req)
+
+	// Nothing is asked to the unreachable model
+	if rec := getStream(id); rec.Code != http.StatusOK {
+		t.Errorf("the cancelled stream returned %d", rec.Code)
+	}
+}
@@ -0,0 +1,365 @@
+// Package translit romanizes arabic text using a few common schemes.
+package translit
+
+import (
+	"strings"
+)
+
+type Scheme string
+
+const (
+	None       Scheme = ""
+	Buckwalter Scheme = "buckwalter"
+	DIN31635   Scheme = "din31635"
+	ALALC      Scheme = "alalc"
+)
+
+type SchemeInfo struct {
+	Scheme Scheme
+	Name   string
+}
+
+func Schemes() []SchemeInfo {
+	return []SchemeInfo{
+		{Scheme: Buckwalter, Name: "Buckwalter"},
+		{Scheme: DIN31635, Name: "DIN 31635"},
+		{Scheme: ALALC, Name: "ALA-LC"},
+	}
+}
+
+// Parse returns the scheme matching the given name, or None if it is unknown.
+func Parse(name string) Scheme {
+	for _, s := range Schemes() {
+		if string(s.Scheme) == name {
+			return s.Scheme
+		}
+	}
+
+	return None
+}
+
+const (
+	fathatan = 'ً'
+	dammatan = 'ٌ'
+	kasratan = 'ٍ'
+	fatha    = 'َ'
+	damma    = 'ُ'
+	kasra    = 'ِ'
+	shadda   = 'ّ'
+	sukun    = 'ْ'
+	dagger   = 'ٰ'
+	tatweel  = 'ـ'
+)
+
+var buckwalter = map[rune]string{
+	'ء': "'", 'آ': "|", 'أ': ">", 'ؤ': "&", 'إ': "<", 'ئ': "}", 'ا': "A",
+	'ب': "b", 'ة': "p", 'ت': "t", 'ث': "v", 'ج': "j", 'ح': "H", 'خ': "x",
+	'د': "d", 'ذ': "*", 'ر': "r", 'ز': "z", 'س': "s", 'ش': "$", 'ص': "S",
+	'ض': "D", 'ط': "T", 'ظ': "Z", 'ع': "E", 'غ': "g", 'ف': "f", 'ق': "q",
+	'ك': "k", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'و': "w", 'ى': "Y",
+	'ي': "y", 'ٱ': "{",
+	fathatan: "F", dammatan: "N", kasratan: "K", fatha: "a", damma: "u",
+	kasra: "i", shadda: "~", sukun: "o", dagger: "`", tatweel: "_",
+}
+
+// Consonants shared by DIN 31635 and ALA-LC, the differences are in the
+// per scheme tables below.
+var consonants = map[rune]string{
+	'ب': "b", 'ت': "t", 'ح': "ḥ", 'د': "d", 'ر': "r", 'ز': "z", 'س': "s",
+	'ص': "ṣ", 'ض': "ḍ", 'ط': "ṭ", 'ظ': "ẓ", 'ف': "f", 'ق': "q", 'ك': "k",
+	'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'و': "w", 'ي': "y",
+}
+
+type phonetic struct {
+	consonants  map[rune]string
+	hamza       string
+	taMarbuta   string
+	alifMaqsura string
+	// assimilate the article with the following sun letter (aš-šams vs al-shams)
+	assimilate bool
+}
+
+var din = phonetic{
+	consonants: with(consonants, map[rune]string{
+		'ث': "ṯ", 'ج': "ǧ", 'خ': "ḫ", 'ذ': "ḏ", 'ش': "š", 'ع': "ʿ", 'غ': "ġ",
+	}),
+	hamza:       "ʾ",
+	taMarbuta:   "a",
+	alifMaqsura: "ā",
+	assimilate:  true,
+}
+
+var alalc = phonetic{
+	consonants: with(consonants, map[rune]string{
+		'ث': "th", 'ج': "j", 'خ': "kh", 'ذ': "dh", 'ش': "sh", 'ع': "ʻ", 'غ': "gh",
+	}),
+	hamza:       "ʼ",
+	taMarbuta:   "h",
+	alifMaqsura: "á",
+}
+
+func with(base map[rune]string, extra map[rune]string) map[rune]string {
+	out := make(map[rune]string, len(base)+len(extra))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range extra {
+		out[k] = v
+	}
+	return out
+}
+
+var sunLetters = "تثدذرزسشصضطظلن"
+
+// Transliterate romanizes every arabic word in the input, other characters
+// are kept as is. Short vowels are only rendered when the input carries
+// diacritics.
+func Transliterate(input string, scheme Scheme) string {
+	switch scheme {
+	case Buckwalter:
+		return toBuckwalter(input)
+	case DIN31635:
+		return din.transliterate(input)
+	case ALALC:
+		return alalc.transliterate(input)
+	}
+
+	return ""
+}
+
+func toBuckwalter(input string) string {
+	var b strings.Builder
+	for _, r := range input {
+		if s, ok := buckwalter[r]; ok {
+			b.WriteString(s)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fromBuckwalter is the reverse of the buckwalter table, with the marks of
+// the extended Buckwalter used by the Quranic Arabic Corpus.
+var fromBuckwalter = func() map[rune]rune {
+	reverse := map[rune]rune{
+		'^': '\u0653', // maddah above
+		'#': '\u0654', // hamza above
+		':': '\u06DC', // small high seen
+		'@': '\u06DF', // small high rounded zero
+		'"': '\u06E0', // small high upright rectangular zero
+		'[': '\u06E2', // small high meem
+		';': '\u06E3', // small low seen
+		',': '\u06E5', // small waw
+		'.': '\u06E6', // small ya
+		'!': '\u06E8', // small high noon
+		'-': '\u06EA', // empty centre low stop
+		'+': '\u06EB', // empty centre high stop
+		'%': '\u06EC', // rounded high stop with filled centre
+		']': '\u06ED', // small low meem
+	}
+	for r, s := range buckwalter {
+		reverse[[]rune(s)[0]] = r
+	}
+	return reverse
+}()
+
+// FromBuckwalter converts a Buckwalter transliteration back to arabic, the
+// other characters are kept as is.
+func FromBuckwalter(input string) string {
+	var b strings.Builder
+	for _, r := range input {
+		if a, ok := fromBuckwalter[r]; ok {
+			b.WriteRune(a)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isArabicLetter tells whether r is one of the letters of the tables, the
+// tatweel and the letters of the other languages in between are not.
+func isArabicLetter(r rune) bool {
+	return r >= 'ء' && r <= 'غ' || r >= 'ف' && r <= 'ي' || r == 'ٱ'
+}
+
+func isHaraka(r rune) bool {
+	return r >= fathatan && r <= sukun || r == dagger
+}
+
+// skipHarakat returns the index of the first rune from i that isn't a haraka.
+func skipHarakat(runes []rune, i int) int {
+	for i < len(runes) && isHaraka(runes[i]) {
+		i++
+	}
+	return i
+}
+
+// vowelAfter returns the short vowel following the letter at index i, if any.
+func vowelAfter(runes []rune, i int) rune {
+	for j := i + 1; j < len(runes) && isHaraka(runes[j]); j++ {
+		if runes[j] != shadda {
+			return runes[j]
+		}
+	}
+	return 0
+}
+
+func (p phonetic) transliterate(input string) string {
+	runes := []rune(input)
+	var b strings.Builder
+	// last written short vowel, used to merge long vowels
+	var vowel rune
+	wordStart := true
+	// the shadda of a sun letter following the article is already rendered
+	// by the article itself
+	skipShadda := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if !isArabicLetter(r) && !isHaraka(r) && r != tatweel {
+			b.WriteRune(r)
+			wordStart = true
+			vowel = 0
+			continue
+		}
+
+		// Definite article, the alif and the lam may carry their haraka (اَلْ)
+		if lam := skipHarakat(runes, i+1); wordStart && (r == 'ا' || r == 'ٱ') && lam < len(runes) && runes[lam] == 'ل' {
+			next := skipHarakat(runes, lam+1)
+			if next < len(runes) && isArabicLetter(runes[next]) {
+				sun := strings.ContainsRune(sunLetters, runes[next])
+				if p.assimilate && sun && runes[next] != 'ل' {
+					b.WriteString("a" + p.consonants[runes[next]] + "-")
+				} else {
+					b.WriteString("al-")
+				}
+				i = next - 1
+				wordStart = false
+				skipShadda = sun
+				vowel = 0
+				continue
+			}
+		}
+
+		switch r {
+		case fatha:
+			b.WriteString("a")
+			vowel = r
+		case damma:
+			b.WriteString("u")
+			vowel = r
+		case kasra:
+			b.WriteString("i")
+			vowel = r
+		case fathatan:
+			b.WriteString("an")
+			vowel = 0
+		case dammatan:
+			b.WriteString("un")
+			vowel = 0
+		case kasratan:
+			b.WriteString("in")
+			vowel = 0
+		case shadda, sukun, tatweel:
+			// The shadda is rendered along with its consonant.
+		case dagger:
+			b.WriteString("ā")
+			vowel = 0
+		case 'ا', 'ٱ':
+			switch {
+			case wordStart:
+				// The vowel of a word initial alif is carried by its haraka.
+				if vowelAfter(runes, i) == 0 {
+					b.WriteString("a")
+				}
+			case i > 0 && runes[i-1] == fathatan:
+				// Silent alif of the accusative tanwin.
+			case i > 0 && runes[i-1] == 'و' && (i+1 == len(runes) || !isArabicLetter(runes[i+1])):
+				// Silent alif after the plural waw (قالوا)
+			default:
+				p.long(&b, vowel, fatha, "ā")
+			}
+			vowel = 0
+		case 'آ':
+			if !wordStart {
+				b.WriteString(p.hamza)
+			}
+			b.WriteString("ā")
+			vowel = 0
+		case 'ى':
+			p.long(&b, vowel, fatha, p.alifMaqsura)
+			vowel = 0
+		case 'ة':
+			if v := vowelAfter(runes, i); v != 0 && v != sukun {
+				b.WriteString("t")
+			} else {
+				b.WriteString(p.taMarbuta)
+			}
+			vowel = 0
+		case 'ء', 'أ', 'إ', 'ؤ', 'ئ':
+			// DIN and ALA-LC don't write the word initial hamza
+			if !wordStart {
+				b.WriteString(p.hamza)
+			} else if r == 'إ' && vowelAfter(runes, i) == 0 {
+				b.WriteString("i")
+			} else if r == 'أ' && vowelAfter(runes, i) == 0 {
+				b.WriteString("a")
+			}
+			vowel = 0
+		case 'و', 'ي':
+			long := map[rune]struct {
+				vowel rune
+				out   string
+			}{
+				'و': {damma, "ū"},
+				'ي': {kasra, "ī"},
+			}[r]
+			if !wordStart && vowel == long.vowel && vowelAfter(runes, i) == 0 && !followedBy(runes, i, shadda) {
+				p.long(&b, vowel, long.vowel, long.out)
+				vowel = 0
+				break
+			}
+			p.consonant(&b, runes, i, skipShadda)
+			vowel = 0
+		default:
+			p.consonant(&b, runes, i, skipShadda)
+			vowel = 0
+		}
+
+		wordStart = false
+		skipShadda = false
+	}
+
+	return b.String()
+}
+
+func (p phonetic) consonant(b *strings.Builder, runes []rune, i int, skipShadda bool) {
+	c := p.consonants[runes[i]]
+	b.WriteString(c)
+	if !skipShadda && followedBy(runes, i, shadda) {
+		b.WriteString(c)
+	}
+}
+
+func followedBy(runes []rune, i int, target rune) bool {
+	for j := i + 1; j < len(runes) && isHaraka(runes[j]); j++ {
+		if runes[j] == target {
+			return true
+		}
+	}
+	return false
+}
+
+// long writes a long vowel, replacing the short vowel that was just written
+// when it matches (e.g. fatha + alif gives ā instead of aā).
+func (p phonetic) long(b *strings.Builder, current rune, short rune, out string) {
+	if current == short {
+		s := b.String()
+		b.Reset()
+		b.WriteString(s[:len(s)-1])
+	}
+	b.WriteString(out)
+}
@@ -0,0 +1,80 @@
+package translit
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		input      string
+		buckwalter string
+		din        string
+		alalc      string
+	}{
+		{"كِتَابٌ", "kitaAbN", "kitābun", "kitābun"},
+		{"كتاب", "ktAb", "ktāb", "ktāb"},
+		{"الْكِتَابُ", "AlokitaAbu", "al-kitābu", "al-kitābu"},
+		// Vocalized article
+		{"اَلْكِتَابُ", "AalokitaAbu", "al-kitābu", "al-kitābu"},
+		{"اَلشَّمْسُ", "Aal$a~mosu", "aš-šamsu", "al-shamsu"},
+		{"الشمس", "Al$ms", "aš-šms", "al-shms"},
+		{"مَدْرَسَةٌ", "madorasapN", "madrasatun", "madrasatun"},
+		{"قَالُوا", "qaAluwA", "qālū", "qālū"},
+		{"عَلِيّ", "Ealiy~", "ʿaliyy", "ʻaliyy"},
+		{"إِسْلَام", "<isolaAm", "islām", "islām"},
+		{"مُسْلِمُونَ", "musolimuwna", "muslimūna", "muslimūna"},
+		{"ـكتابـ", "_ktAb_", "ktāb", "ktāb"},
+		// Letters of the other languages are kept as is
+		{"ػڭ", "ػڭ", "ػڭ", "ػڭ"},
+		{"كتاب 2", "ktAb 2", "ktāb 2", "ktāb 2"},
+	}
+
+	for _, test := range tests {
+		for scheme, want := range map[Scheme]string{
+			Buckwalter: test.buckwalter,
+			DIN31635:   test.din,
+			ALALC:      test.alalc,
+		} {
+			if got := Transliterate(test.input, scheme); got != want {
+				t.Errorf("Transliterate(%q, %s) = %q, want %q", test.input, scheme, got, want)
+			}
+		}
+	}
+}
+
+func TestTransliterateNone(t *testing.T) {
+	if got := Transliterate("كتاب", None); got != "" {
+		t.Errorf("Transliterate with no scheme = %q, want an empty string", got)
+	}
+}
+
+func TestFromBuckwalter(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"kitAbN", "كِتابٌ"},
+		{"Al$~amosu", "\u0627\u0644\u0634\u0651\u064e\u0645\u0652\u0633\u064f"},
+		{"{lr~aHoma`ni", "\u0671\u0644\u0631\u0651\u064e\u062d\u0652\u0645\u064e\u0670\u0646\u0650"},
+		// Extended marks of the Quranic Arabic Corpus
+		{"bihi,", "\u0628\u0650\u0647\u0650\u06e5"},
+		{"fiyhi.", "\u0641\u0650\u064a\u0647\u0650\u06e6"},
+		{":@\"[;!-+%]", "\u06dc\u06df\u06e0\u06e2\u06e3\u06e8\u06ea\u06eb\u06ec\u06ed"},
+		{"123", "123"},
+	}
+
+	for _, test := range tests {
+		if got := FromBuckwalter(test.input); got != test.want {
+			t.Errorf("FromBuckwalter(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range Schemes() {
+		if got := Parse(string(s.Scheme)); got != s.Scheme {
+			t.Errorf("Parse(%q) = %q", s.Scheme, got)
+		}
+	}
+	if got := Parse("unknown"); got != None {
+		t.Errorf("Parse(unknown) = %q, want None", got)
+	}
+}
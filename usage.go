@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/store"
+	"strconv"
+	"time"
+)
+
+// usageTracker records the cost of the LLM requests and enforces the monthly budget.
+type usageTracker struct {
+	store  *store.Store
+	prices map[string]clients.Price
+	// Monthly budget in dollars, 0 if unlimited
+	budget float64
+	// Monthly budget of each user in dollars, 0 if unlimited
+	userBudget float64
+}
+
+// newUsageTracker reads the price table from the JSON file of SAHIB_PRICES,
+// the monthly budget from SAHIB_MONTHLY_BUDGET and the one of each user from
+// SAHIB_USER_BUDGET.
+func newUsageTracker(st *store.Store) (*usageTracker, error) {
+	prices, err := clients.LoadPrices(os.Getenv("SAHIB_PRICES"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &usageTracker{store: st, prices: prices}
+	for name, budget := range map[string]*float64{"SAHIB_MONTHLY_BUDGET": &t.budget, "SAHIB_USER_BUDGET": &t.userBudget} {
+		if v := os.Getenv(name); v != "" {
+			*budget, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+func (t *usageTracker) RecordUsage(u model.LLMUsage) error {
+	price, ok := t.prices[u.Model]
+	if !ok {
+		log.Printf("No price for the %s model, its usage is considered free", u.Model)
+	}
+	u.Cost = price.Cost(u.PromptTokens, u.CompletionTokens)
+
+	return t.store.AddUsage(u)
+}
+
+func startOfMonth(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// CheckBudget returns an error if the LLM requests of the month already cost
+// more than the budget or than the budget of the user, in which case the LLM
+// sources are disabled. The requests of the anonymous visitors and of the
+// server itself (the diacritizer) only count in the global budget.
+func (t *usageTracker) CheckBudget(user string) error {
+	month := startOfMonth(time.Now())
+	if t.budget > 0 {
+		cost, err := t.store.UsageCost(month)
+		if err != nil {
+			log.Printf("Failed to check the LLM budget: %s", err)
+		} else if cost >= t.budget {
+			return fmt.Errorf("the monthly LLM budget is exceeded, see the usage page")
+		}
+	}
+
+	if t.userBudget > 0 && user != "" {
+		cost, err := t.store.UserUsageCost(user, month)
+		if err != nil {
+			log.Printf("Failed to check the LLM budget of %s: %s", user, err)
+		} else if cost >= t.userBudget {
+			return fmt.Errorf("your monthly LLM budget is exceeded, see the usage page")
+		}
+	}
+
+	return nil
+}
+
+// allowed returns why the user can't use the LLM sources, nil if they can.
+// Once users are configured, the anonymous visitors can't.
+func (t *usageTracker) allowed(a *auth, user string) error {
+	if user == "" && a.hasUsers() {
+		return fmt.Errorf("log in to use the LLM sources")
+	}
+	return t.CheckBudget(user)
+}
+
+// handleUsage shows the usage of every user to the administrator, and their
+// own usage to the users.
+func (t *usageTracker) handleUsage(a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := a.identify(r)
+		if name == "" {
+			http.Redirect(w, r, "/login?"+url.Values{model.Next: {r.URL.Path}}.Encode(), http.StatusSeeOther)
+			return
+		}
+
+		now := time.Now()
+		month := startOfMonth(now)
+
+		var days, users []model.UsageTotal
+		var err error
+		budget := t.budget
+		if name == adminName {
+			days, err = t.store.UsageByDay(now.AddDate(0, 0, -30))
+			if err == nil {
+				users, err = t.store.UsageByUser(month)
+			}
+		} else {
+			budget = t.userBudget
+			days, err = t.store.UserUsageByDay(name, now.AddDate(0, 0, -30))
+			if err == nil {
+				users, err = t.store.UserUsage(name, month)
+			}
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var cost float64
+		for _, u := range users {
+			cost += u.Cost
+		}
+
+		component := components.Usage(days, users, cost, budget)
+		component.Render(r.Context(), w)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sahib/store"
+	"testing"
+)
+
+func TestUsagePage(t *testing.T) {
+	a := newTestAuth(t, "secret")
+	st, err := store.Open(filepath.Join(t.TempDir(), "sahib.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	handler := (&usageTracker{store: st}).handleUsage(a)
+
+	for session, want := range map[string]int{"": http.StatusSeeOther, a.session("amina"): http.StatusOK, a.session(adminName): http.StatusOK} {
+		r := httptest.NewRequest("GET", "/usage", nil)
+		if session != "" {
+			withSession(r, session)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		if rec.Code != want {
+			t.Errorf("session %q: got %d, want %d", session, rec.Code, want)
+		}
+	}
+}
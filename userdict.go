@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userDictionary is the query function of the user dictionary source.
+func userDictionary(st *store.Store) func(word string, lang model.Language) (*model.Translations, error) {
+	return func(word string, lang model.Language) (*model.Translations, error) {
+		result := &model.Translations{Link: "/dictionary"}
+		start := time.Now()
+		defer func() {
+			result.Elapsed = clients.Elapsed(start)
+		}()
+
+		entries, err := st.MatchUserEntries(word, lang.Code)
+		if err != nil {
+			return result, err
+		}
+
+		for _, e := range entries {
+			meta := []string{}
+			for _, v := range []string{e.Domain, e.Notes} {
+				if v != "" {
+					meta = append(meta, v)
+				}
+			}
+			result.List = append(result.List, model.Translation{
+				Arabic:      e.Arabic,
+				Translation: e.Translation,
+				Meta:        strings.Join(meta, " · "),
+			})
+		}
+
+		return result, nil
+	}
+}
+
+// validateUserEntry trims the fields of the entry and checks the mandatory ones.
+func validateUserEntry(e *model.UserEntry) error {
+	e.Arabic = strings.TrimSpace(e.Arabic)
+	e.Translation = strings.TrimSpace(e.Translation)
+	e.Domain = strings.TrimSpace(e.Domain)
+	e.Notes = strings.TrimSpace(e.Notes)
+
+	if e.Arabic == "" || e.Translation == "" {
+		return fmt.Errorf("the entry must have an arabic word and a translation")
+	}
+	if e.Language != "" {
+		lang, ok := model.FindLanguage(e.Language)
+		if !ok {
+			return fmt.Errorf("unknown language: %s", e.Language)
+		}
+		e.Language = lang.Code
+	}
+
+	return nil
+}
+
+func userEntryFromForm(r *http.Request) model.UserEntry {
+	e := model.UserEntry{
+		Arabic:      r.FormValue("arabic"),
+		Translation: r.FormValue("translation"),
+		Language:    r.FormValue("language"),
+		Domain:      r.FormValue("domain"),
+		Notes:       r.FormValue("notes"),
+	}
+	e.ID, _ = strconv.ParseInt(r.FormValue("id"), 10, 64)
+	return e
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write JSON response: %s", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// registerUserDictionaryHandlers registers the pages and the JSON API of the
+// user dictionary, the pages are open to the logged in users and the API to
+// the scripts sending their token.
+func registerUserDictionaryHandlers(auth *auth, st *store.Store) {
+	renderEntries := func(w http.ResponseWriter, r *http.Request, editing *model.UserEntry, formErr string) {
+		query := r.FormValue(model.Search)
+		entries, err := st.UserEntries(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if editing == nil {
+			editing = &model.UserEntry{}
+		}
+
+		component := components.UserDictionary(entries, query, *editing, formErr, auth.csrfToken(auth.identify(r)))
+		component.Render(r.Context(), w)
+	}
+
+	http.HandleFunc("GET /dictionary", auth.requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		renderEntries(w, r, nil, "")
+	}))
+
+	http.HandleFunc("GET /dictionary/{id}", auth.requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		e, err := st.UserEntry(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if e == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		renderEntries(w, r, e, "")
+	}))
+
+	http.HandleFunc("POST /dictionary", auth.requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		e := userEntryFromForm(r)
+		err := validateUserEntry(&e)
+		if err == nil {
+			err = st.SaveUserEntry(&e)
+		}
+		if err != nil {
+			renderEntries(w, r, &e, err.Error())
+			return
+		}
+
+		log.Printf("Saved user entry %s (%d)", e.Arabic, e.ID)
+		http.Redirect(w, r, "/dictionary", http.StatusSeeOther)
+	}))
+
+	http.HandleFunc("POST /dictionary/{id}/delete", auth.requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err := st.DeleteUserEntry(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/dictionary", http.StatusSeeOther)
+	}))
+
+	http.HandleFunc("GET /api/dictionary", auth.requireToken(func(w http.ResponseWriter, r *http.Request) {
+		entries, err := st.UserEntries(r.FormValue("q"))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, entries)
+	}))
+
+	http.HandleFunc("GET /api/dictionary/{id}", auth.requireToken(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		e, err := st.UserEntry(id)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if e == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("user entry %d doesn't exist", id))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, e)
+	}))
+
+	// Creates the entry with POST and replaces it with PUT
+	saveEntry := func(w http.ResponseWriter, r *http.Request) {
+		e := model.UserEntry{}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid entry: %w", err))
+			return
+		}
+
+		status := http.StatusCreated
+		e.ID = 0
+		if r.Method == http.MethodPut {
+			status = http.StatusOK
+			e.ID, _ = strconv.ParseInt(r.PathValue("id"), 10, 64)
+			existing, err := st.UserEntry(e.ID)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if existing == nil {
+				writeJSONError(w, http.StatusNotFound, fmt.Errorf("user entry %d doesn't exist", e.ID))
+				return
+			}
+		}
+
+		if err := validateUserEntry(&e); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := st.SaveUserEntry(&e); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		saved, err := st.UserEntry(e.ID)
+		if err != nil || saved == nil {
+			saved = &e
+		}
+		writeJSON(w, status, saved)
+	}
+	http.HandleFunc("POST /api/dictionary", auth.requireToken(saveEntry))
+	http.HandleFunc("PUT /api/dictionary/{id}", auth.requireToken(saveEntry))
+
+	http.HandleFunc("DELETE /api/dictionary/{id}", auth.requireToken(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err := st.DeleteUserEntry(id); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
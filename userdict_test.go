@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sahib/clients"
+	"sahib/model"
+	"strings"
+	"testing"
+)
+
+func TestUserDictionaryAuth(t *testing.T) {
+	st := openTestStore(t)
+	a := newTestAuth(t, "secret")
+	registerUserDictionaryHandlers(a, st)
+	amina := a.session("amina")
+
+	form := func(path, session, csrf string) *http.Request {
+		values := url.Values{"arabic": {"مُحامٍ"}, "translation": {"lawyer"}, model.CSRF: {csrf}}
+		r := httptest.NewRequest("POST", path, strings.NewReader(values.Encode()))
+		r.Header.Set(clients.ContentType, "application/x-www-form-urlencoded")
+		if session != "" {
+			withSession(r, session)
+		}
+		return r
+	}
+	api := func(method, path, token string) *http.Request {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{"arabic": "طَبِيب", "translation": "doctor"}`))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+	withCookie := api("POST", "/api/dictionary", "")
+	withSession(withCookie, amina)
+
+	// In order, the entries 1 and 2 are created and the entry 2 deleted
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    int
+	}{
+		{"anonymous page", httptest.NewRequest("GET", "/dictionary", nil), http.StatusSeeOther},
+		{"user page", withSession(httptest.NewRequest("GET", "/dictionary", nil), amina), http.StatusOK},
+		{"anonymous form", form("/dictionary", "", ""), http.StatusUnauthorized},
+		{"missing CSRF token", form("/dictionary", amina, ""), http.StatusForbidden},
+		{"CSRF token of another session", form("/dictionary", amina, a.csrfToken(adminName)), http.StatusForbidden},
+		{"user form", form("/dictionary", amina, a.csrfToken("amina")), http.StatusSeeOther},
+		{"anonymous delete", form("/dictionary/1/delete", "", ""), http.StatusUnauthorized},
+		{"delete without CSRF token", form("/dictionary/1/delete", amina, ""), http.StatusForbidden},
+		{"anonymous API", api("POST", "/api/dictionary", ""), http.StatusUnauthorized},
+		{"session on the API", withCookie, http.StatusUnauthorized},
+		{"wrong token", api("GET", "/api/dictionary", "amina"), http.StatusUnauthorized},
+		{"user token", api("POST", "/api/dictionary", "amina-token"), http.StatusCreated},
+		{"admin token", api("PUT", "/api/dictionary/2", "admin-token"), http.StatusOK},
+		{"anonymous API delete", api("DELETE", "/api/dictionary/2", ""), http.StatusUnauthorized},
+		{"API delete", api("DELETE", "/api/dictionary/2", "amina-token"), http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(rec, test.request)
+		if rec.Code != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, rec.Code, test.want)
+		}
+	}
+
+	entries, err := st.UserEntries("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Arabic != "مُحامٍ" {
+		t.Errorf("entries = %+v, want the one of the form", entries)
+	}
+}
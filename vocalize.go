@@ -0,0 +1,147 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sahib/clients"
+	"sahib/components"
+	"sahib/model"
+	"sahib/translit"
+	"sync"
+	"time"
+)
+
+// How long a vocalization result is kept if the browser never fetches it.
+const vocalizeJobTTL = 5 * time.Minute
+
+var diacritizer *clients.CachedDiacritizer
+
+// newDiacritizer returns the diacritizer configured with the SAHIB_DIACRITIZER
+// environment variable: tashkil (default) or llm, the LLM being configured
+// with the SAHIB_DIACRITIZER_* variables (see clients.LLMFromEnv).
+func newDiacritizer(usage clients.UsageRecorder) (clients.Diacritizer, error) {
+	switch os.Getenv("SAHIB_DIACRITIZER") {
+	case "llm":
+		llm, err := clients.LLMFromEnv("SAHIB_DIACRITIZER")
+		if err != nil {
+			return nil, err
+		}
+		if llm == nil {
+			preset := clients.LLMPresets()["llamacpp"]
+			llm = &preset
+		}
+		return &clients.LLMDiacritizer{LLM: llm.WithUsage(usage, "")}, nil
+	default:
+		return clients.TashkilDiacritizer{}, nil
+	}
+}
+
+// vocalizeJob adds the diacritics to the arabic column of results in the
+// background, the rows are rendered once without them and swapped when the
+// browser fetches the job.
+type vocalizeJob struct {
+	done chan struct{}
+	rows []model.Translation
+}
+
+var vocalizeJobs = struct {
+	sync.Mutex
+	m map[string]*vocalizeJob
+}{m: map[string]*vocalizeJob{}}
+
+func arabicColumn(rows []model.Translation) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = row.Arabic
+	}
+	return out
+}
+
+func withArabic(rows []model.Translation, arabic []string, scheme translit.Scheme) []model.Translation {
+	out := make([]model.Translation, len(rows))
+	copy(out, rows)
+	for i := range out {
+		out[i].Arabic = arabic[i]
+		if scheme != translit.None {
+			out[i].Translit = translit.Transliterate(arabic[i], scheme)
+		}
+	}
+	return out
+}
+
+// vocalizable tells whether the diacritics of the results are worth adding:
+// the cards of the dictionaries with their own sections don't show the rows,
+// and Elixir already vocalizes its answers.
+func vocalizable(ts model.TranslationsAndSource) bool {
+	res := ts.Translations
+	switch {
+	case res.Error != "" || res.Stream != "":
+		return false
+	case ts.Source == model.SourceElixir:
+		return false
+	case len(res.Wiktionary) > 0 || len(res.Classical) > 0 || len(res.Monolingual) > 0:
+		return false
+	}
+	return true
+}
+
+// vocalize adds the diacritics to the results directly if they are cached and
+// otherwise starts a background job whose id is set as the results Pending field.
+func vocalize(res *model.Translations, lang model.Language, scheme translit.Scheme) {
+	if len(res.List) == 0 {
+		return
+	}
+
+	arabic := arabicColumn(res.List)
+	if vocalized, ok := diacritizer.Cached(arabic, lang); ok {
+		res.List = withArabic(res.List, vocalized, scheme)
+		return
+	}
+
+	id := newJobID()
+	job := &vocalizeJob{done: make(chan struct{}), rows: res.List}
+	vocalizeJobs.Lock()
+	vocalizeJobs.m[id] = job
+	vocalizeJobs.Unlock()
+	res.Pending = id
+
+	go func() {
+		defer close(job.done)
+		vocalized, err := diacritizer.Diacritize(arabic, lang)
+		if err != nil {
+			log.Printf("Couldn't add the diacritics to results: %s", err)
+			return
+		}
+		job.rows = withArabic(job.rows, vocalized, scheme)
+	}()
+
+	time.AfterFunc(vocalizeJobTTL, func() {
+		vocalizeJobs.Lock()
+		delete(vocalizeJobs.m, id)
+		vocalizeJobs.Unlock()
+	})
+}
+
+func handleVocalize(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	vocalizeJobs.Lock()
+	job, ok := vocalizeJobs.m[id]
+	delete(vocalizeJobs.m, id)
+	vocalizeJobs.Unlock()
+
+	if !ok {
+		http.Error(w, "unknown vocalization job: "+id, http.StatusNotFound)
+		return
+	}
+
+	select {
+	case <-job.done:
+	case <-r.Context().Done():
+		return
+	}
+
+	component := components.ResultRows(job.rows)
+	component.Render(r.Context(), w)
+}
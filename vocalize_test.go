@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sahib/clients"
+	"sahib/model"
+	"sahib/translit"
+	"testing"
+)
+
+// shaddaDiacritizer adds a shadda after the last letter of the sentences.
+type shaddaDiacritizer struct {
+	calls int
+}
+
+func (d *shaddaDiacritizer) Diacritize(sentences []string, lang model.Language) ([]string, error) {
+	d.calls++
+	out := make([]string, len(sentences))
+	for i, s := range sentences {
+		out[i] = s + "ّ"
+	}
+	return out, nil
+}
+
+func fetchVocalized(id string) int {
+	req := httptest.NewRequest("GET", "/vocalize/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handleVocalize(rec, req)
+	return rec.Code
+}
+
+func TestVocalize(t *testing.T) {
+	fake := &shaddaDiacritizer{}
+	diacritizer = clients.NewCachedDiacritizer(fake)
+	lang := model.Language{Code: "fr", Name: "French"}
+
+	res := &model.Translations{List: []model.Translation{{Arabic: "حب", Translation: "amour"}}}
+	vocalize(res, lang, translit.None)
+	if res.Pending == "" || res.List[0].Arabic != "حب" {
+		t.Fatalf("the rows should be rendered first and vocalized in a job, got %+v", res)
+	}
+
+	// The job is fetched once by the browser
+	if code := fetchVocalized(res.Pending); code != http.StatusOK {
+		t.Errorf("first fetch returned %d", code)
+	}
+	if code := fetchVocalized(res.Pending); code != http.StatusNotFound {
+		t.Errorf("second fetch returned %d, want %d", code, http.StatusNotFound)
+	}
+
+	// The next search gets the cached diacritics directly
+	again := &model.Translations{List: []model.Translation{{Arabic: "حب", Translation: "amour"}}}
+	vocalize(again, lang, translit.None)
+	if again.Pending != "" || again.List[0].Arabic != "حبّ" {
+		t.Errorf("the cached diacritics should be used, got %+v", again)
+	}
+	if fake.calls != 1 {
+		t.Errorf("the diacritizer was called %d times, want 1", fake.calls)
+	}
+}
+
+func TestVocalizable(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   model.TranslationsAndSource
+		want bool
+	}{
+		{"rows", model.TranslationsAndSource{Source: model.SourceMaany, Translations: &model.Translations{}}, true},
+		{"error", model.TranslationsAndSource{Source: model.SourceMaany, Translations: &model.Translations{Error: "timeout"}}, false},
+		{"elixir", model.TranslationsAndSource{Source: model.SourceElixir, Translations: &model.Translations{}}, false},
+		{"own sections", model.TranslationsAndSource{Source: model.SourceLane, Translations: &model.Translations{Classical: []model.ClassicalRoot{{Root: "حبب"}}}}, false},
+	}
+
+	for _, test := range tests {
+		if got := vocalizable(test.ts); got != test.want {
+			t.Errorf("%s: vocalizable() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}